@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"task-manager/internal/config"
+	"task-manager/migrations"
+	"task-manager/pkg/clients/posgresql"
+	"task-manager/pkg/logger/handlers/slogpretty"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+const usage = `Использование: migrator <команда>
+
+Команды:
+  up             применить все непримененные миграции
+  down [n]       откатить n последних миграций (по умолчанию 1)
+  status         показать состояние миграций
+  goto <версия>  привести схему к указанной версии (0 - откатить все)`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	log := setupLogger()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cnf := config.New()
+	dbClient, err := posgresql.NewDBClient(ctx, cnf, log)
+	if err != nil {
+		log.Error("Не удалось создать клиента базы данных", sl.Err(err))
+		os.Exit(1)
+	}
+	defer dbClient.Close()
+
+	migrator := migrations.NewMigrator(log, dbClient)
+
+	switch os.Args[1] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			if steps, err = strconv.Atoi(os.Args[2]); err != nil || steps < 1 {
+				fmt.Println(usage)
+				os.Exit(2)
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "goto":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		version, parseErr := strconv.ParseInt(os.Args[2], 10, 64)
+		if parseErr != nil || version < 0 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		err = migrator.Goto(ctx, version)
+	case "status":
+		err = printStatus(ctx, migrator)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Error("Ошибка выполнения миграций", sl.Err(err))
+		dbClient.Close()
+		os.Exit(1)
+	}
+
+	log.Info("Миграции выполнены", slog.String("command", os.Args[1]))
+}
+
+func printStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		appliedAt := "не применена"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Printf("%04d  %-30s  %s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return nil
+}
+
+func setupLogger() *slog.Logger {
+	opts := slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		},
+	}
+
+	return slog.New(opts.NewPrettyHandler(os.Stdout))
+}
@@ -2,29 +2,37 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-chi/chi"
-	"github.com/go-chi/chi/middleware"
-	"github.com/go-chi/jwtauth/v5"
+	chimiddleware "github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"task-manager/internal/app"
+	"task-manager/internal/auth/policy"
 	"task-manager/internal/auth/repo"
 	"task-manager/internal/auth/transport/transport_http"
 	"task-manager/internal/auth/usecases"
 	"task-manager/internal/config"
+	tasksrepo "task-manager/internal/tasks/repo"
+	taskshttp "task-manager/internal/tasks/transport/transport_http"
+	tasksusecases "task-manager/internal/tasks/usecases"
+	categoryrepo "task-manager/internal/tasks_categories/repo"
+	categoryusecases "task-manager/internal/tasks_categories/usecases"
 	"task-manager/pkg/clients/kafka"
 	"task-manager/pkg/clients/posgresql"
+	"task-manager/pkg/events"
+	jwtissuer "task-manager/pkg/jwt"
 	"task-manager/pkg/logger/handlers/slogpretty"
+	"task-manager/pkg/mailer"
+	"task-manager/pkg/oidc"
+	"task-manager/pkg/outbox"
+	"task-manager/pkg/password"
+	"time"
 )
 
-var tokenAuth *jwtauth.JWTAuth
-
-func init() {
-	tokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
-}
-
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -36,28 +44,149 @@ func main() {
 
 	DBClient, err := posgresql.NewDBClient(ctx, cnf, log)
 	if err != nil {
-		log.Error("Не удалось создать клиента базы данных: error", err)
+		log.Error("Не удалось создать клиента базы данных", slog.Any("err", err))
+	}
+
+	codec, err := events.NewCodec(cnf.EventFormat)
+	if err != nil {
+		log.Error("Ошибка настройки формата событий", slog.Any("err", err))
+		os.Exit(1)
 	}
 
-	producer, err := kafka.NewKafkaProducer(log, cnf.Brokers, cnf.Topic)
+	// недоступная Kafka запуску не мешает: события ждут в outbox
+	producer, err := kafka.NewKafkaProducer(log, cnf.Brokers, cnf.Topic, codec)
 	if err != nil {
 		log.Error("Ошибка создания Kafka продюсера", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	// сервисы записывают события в outbox в транзакции с изменением,
+	// в Kafka их переносит relay
+	transactor := posgresql.NewTransactor(DBClient)
+	eventStore := outbox.NewStore(DBClient)
+	relay := outbox.NewRelay(log, DBClient, producer, outbox.RelayPolicy{
+		Interval:        cnf.OutboxRelayInterval,
+		BatchSize:       cnf.OutboxBatchSize,
+		BackoffBase:     cnf.OutboxBackoffBase,
+		BackoffMax:      cnf.OutboxBackoffMax,
+		Retention:       cnf.OutboxRetention,
+		CleanupInterval: cnf.OutboxCleanupInterval,
+	})
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(relayDone)
+	}()
+
+	issuer, err := jwtissuer.NewIssuer(cnf.JWT)
+	if err != nil {
+		log.Error("Ошибка загрузки ключей подписи токенов", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	hasher, err := password.NewHasher(cnf.PasswordHashing)
+	if err != nil {
+		log.Error("Ошибка настройки хеширования паролей", slog.Any("err", err))
+		os.Exit(1)
+	}
+
+	credentialPolicy, err := policy.New(cnf.CredentialPolicy)
+	if err != nil {
+		log.Error("Ошибка загрузки политики паролей", slog.Any("err", err))
+		os.Exit(1)
 	}
 
 	userRepository := repo.NewRepository(DBClient)
-	userService := usecases.NewUserService(log, userRepository, producer)
+	auditService := usecases.NewAuditService(log, userRepository)
+	tokenService := usecases.NewTokenService(
+		log, userRepository, userRepository, userRepository, userRepository, issuer,
+		cnf.TokenTTL, cnf.RefreshTokenTTL, cnf.MFATokenTTL,
+	)
+	userService := usecases.NewUserService(
+		log, userRepository, eventStore, transactor, tokenService, hasher, credentialPolicy, auditService,
+		cnf.RequireEmailVerification,
+	)
+	mail := setupMailer(cnf, log)
+	recoveryService := usecases.NewRecoveryService(
+		log, userRepository, userRepository, mail, tokenService, eventStore, transactor, hasher, credentialPolicy,
+		auditService,
+		cnf.PasswordResetTTL, cnf.PublicURL,
+	)
+	verificationService := usecases.NewVerificationService(
+		log, userRepository, userRepository, mail, eventStore, transactor,
+		cnf.EmailVerificationTTL, cnf.PublicURL,
+	)
+	mfaService := usecases.NewMFAService(log, userRepository, userRepository, eventStore, hasher, cnf.JWT.Issuer)
+	throttler := usecases.NewLoginThrottler(log, setupLoginAttemptStore(cnf, userRepository), eventStore, auditService, usecases.ThrottlePolicy{
+		FreeFailures:     cnf.LoginFreeFailures,
+		BackoffBase:      cnf.LoginBackoffBase,
+		BackoffMax:       cnf.LoginBackoffMax,
+		LoginMaxFailures: cnf.LoginMaxFailures,
+		IPMaxFailures:    cnf.IPMaxFailures,
+		Lockout:          cnf.LoginLockout,
+		Window:           cnf.LoginFailureWindow,
+	})
+	go throttler.RunCleanup(ctx, time.Minute)
+	adminService := usecases.NewAdminService(
+		log, userRepository, tokenService, recoveryService, eventStore, transactor, hasher, credentialPolicy, auditService,
+	)
+	apiKeyService := usecases.NewAPIKeyService(log, userRepository, userRepository, eventStore)
+	oidcService := setupOIDC(ctx, cnf, log, userRepository, credentialPolicy, eventStore, auditService)
+	if cnf.BootstrapAdminLogin != "" {
+		err := adminService.Bootstrap(ctx, cnf.BootstrapAdminLogin, cnf.BootstrapAdminPassword, cnf.BootstrapAdminEmail)
+		if err != nil {
+			log.Error("Ошибка создания первого администратора", slog.Any("err", err))
+			os.Exit(1)
+		}
+	}
+
+	taskRepository := tasksrepo.NewRepository(DBClient, log)
+	taskService := tasksusecases.NewTaskService(log, taskRepository, eventStore, transactor)
+
+	categoryRepository := categoryrepo.NewRepository(DBClient, log)
+	categoryService := categoryusecases.NewCategoryService(log, categoryRepository)
 
 	router := chi.NewRouter()
+	// RequestID из chi/v5, как и middleware.GetReqID в обработчиках: у версий
+	// разные ключи контекста. URLFormat работает с контекстом маршрутизатора,
+	// поэтому остается из той же версии, что и chi.NewRouter
 	router.Use(middleware.RequestID)
+	if cnf.TrustProxyHeaders {
+		router.Use(middleware.RealIP)
+	}
+	router.Use(transport_http.RequestMeta())
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	router.Use(chimiddleware.URLFormat)
 
-	transport_http.UsersRoutes(router, log, userService, tokenAuth)
+	authenticate := transport_http.Authenticate(log, issuer, tokenService, apiKeyService)
+	adminOnly := chi.Chain(
+		authenticate,
+		transport_http.RequireRole(log, repo.RoleAdmin),
+		transport_http.RequireScope(usecases.ScopeAdmin),
+	).Handler
+	transport_http.UsersRoutes(
+		router, log, userService, tokenService, recoveryService, verificationService, mfaService, apiKeyService,
+		oidcService, auditService, throttler, issuer, authenticate,
+	)
+	transport_http.AdminRoutes(router, log, adminService, auditService, adminOnly)
+	taskshttp.TasksRoutes(router, log, taskService, authenticate, adminOnly)
 
-	application := app.New(log, router, cnf)
+	application := app.New(log, router, cnf, issuer, tokenService, apiKeyService, taskService, categoryService)
 	go application.GRPCSrv.MustRun()
 	go application.HTTPServer.MustRun()
 
+	consumer := setupConsumer(cnf, log)
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if consumer == nil {
+			return
+		}
+		if err := consumer.Run(ctx); err != nil {
+			log.Error("Чтение событий остановлено", slog.Any("err", err))
+		}
+	}()
+
 	// graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
@@ -69,11 +198,26 @@ func main() {
 	// остановка GRPC-сервера
 	application.GRPCSrv.Stop()
 
+	// relay дописывает текущую пачку до закрытия продюсера, потребитель
+	// дожидается обработчиков и фиксирует смещения обработанных сообщений
+	cancel()
+	<-relayDone
+	<-consumerDone
+	if consumer != nil {
+		if err := consumer.Close(); err != nil {
+			log.Error("Ошибка остановки Kafka-потребителя", slog.Any("err", err))
+		} else {
+			log.Info("Kafka-потребитель успешно остановлен")
+		}
+	}
+
 	// Закрытие Kafka producer
-	if err := producer.Close(); err != nil {
-		log.Error("Ошибка остановки Kafka-продюсера", slog.Any("err", err))
-	} else {
-		log.Info("Kafka-продюсер успешно остановлен")
+	if producer != nil {
+		if err := producer.Close(); err != nil {
+			log.Error("Ошибка остановки Kafka-продюсера", slog.Any("err", err))
+		} else {
+			log.Info("Kafka-продюсер успешно остановлен")
+		}
 	}
 
 	// Закрытие клиента Базы данных
@@ -82,12 +226,90 @@ func main() {
 
 	log.Info("Программа завершена")
 
-	// TODO GRPC ручки для tasks и tasks_categories
-	// TODO реализовать нормальные миграции
-	// TODO подтверждение сообщения после прочтения
 	// TODO написать тесты для ручек с моками(mockery)
 	// TODO написать функциональные тесты
-	// TODO переработать получение токена
+}
+
+// setupMailer Выбирает способ отправки писем по MAIL_DRIVER. Значение проверено
+// в config: file возможен только при ENV=local
+func setupMailer(cnf *config.Config, log *slog.Logger) mailer.Mailer {
+	if cnf.MailDriver == "smtp" {
+		return mailer.NewSMTPMailer(cnf.SMTPHost, cnf.SMTPPort, cnf.SMTPUser, cnf.SMTPPassword, cnf.MailFrom)
+	}
+
+	return mailer.NewFileMailer(log, cnf.MailDir)
+}
+
+// setupLoginAttemptStore Выбирает хранилище счетчиков входа по LOGIN_ATTEMPTS_STORE
+func setupLoginAttemptStore(cnf *config.Config, userRepository *repo.Repository) usecases.LoginAttemptStore {
+	if cnf.LoginAttemptsStore == "postgres" {
+		return userRepository
+	}
+
+	return repo.NewMemoryLoginAttempts()
+}
+
+// setupConsumer Создает группу потребителей и регистрирует обработчики событий,
+// nil - чтение отключено
+func setupConsumer(cnf *config.Config, log *slog.Logger) *kafka.Consumer {
+	if cnf.ConsumerGroup == "" {
+		return nil
+	}
+
+	topics := cnf.ConsumerTopics
+	if len(topics) == 0 {
+		topics = []string{cnf.Topic}
+	}
+	consumer, err := kafka.NewKafkaConsumer(log, cnf.Brokers, cnf.ConsumerGroup, topics, kafka.ConsumerPolicy{
+		RetryBackoffBase: cnf.ConsumerRetryBase,
+		RetryBackoffMax:  cnf.ConsumerRetryMax,
+	})
+	if err != nil {
+		log.Error("Ошибка создания Kafka потребителя", slog.Any("err", err))
+		return nil
+	}
+
+	consumer.Handle(events.TypeUserLoginLocked, kafka.HandlerFunc(func(ctx context.Context, event *events.Event) error {
+		var locked events.UserLoginLocked
+		if err := event.DecodePayload(&locked); err != nil {
+			return fmt.Errorf("%w: %w", kafka.ErrSkipMessage, err)
+		}
+		log.Warn("Вход заблокирован после неудачных попыток",
+			slog.String("event_id", event.ID),
+			slog.String("login", locked.Login),
+			slog.String("key", locked.Key),
+			slog.Int("failures", locked.Failures),
+			slog.Time("until", locked.Until),
+		)
+		return nil
+	}))
+
+	log.Info("Включено чтение событий", slog.String("group", cnf.ConsumerGroup), slog.Any("topics", topics))
+	return consumer
+}
+
+// setupOIDC Настраивает вход через внешнего провайдера, nil - вход отключен
+func setupOIDC(
+	ctx context.Context,
+	cnf *config.Config,
+	log *slog.Logger,
+	userRepository *repo.Repository,
+	credentialPolicy *policy.Policy,
+	producer usecases.Producer,
+	audit usecases.AuditRecorder,
+) *usecases.OIDCService {
+	if cnf.OIDCIssuerURL == "" {
+		return nil
+	}
+
+	service := usecases.NewOIDCService(
+		log, oidc.NewProvider(cnf.OIDC), userRepository, userRepository, credentialPolicy, producer, audit,
+		cnf.OIDCProviderName, cnf.OIDCAutoProvision, cnf.OIDCStateTTL,
+	)
+	go service.RunCleanup(ctx, time.Minute)
+
+	log.Info("Включен вход через OIDC", slog.String("issuer", cnf.OIDCIssuerURL))
+	return service
 }
 
 // SetupLogger Устанавливает логгер
@@ -5,7 +5,11 @@ import (
 	"log/slog"
 	grpcapp "task-manager/internal/app/grpc"
 	httpapp "task-manager/internal/app/http"
+	authgrpc "task-manager/internal/auth/transport/transport_grpc"
 	"task-manager/internal/config"
+	taskusecases "task-manager/internal/tasks/usecases"
+	categoryusecases "task-manager/internal/tasks_categories/usecases"
+	jwtissuer "task-manager/pkg/jwt"
 )
 
 type App struct {
@@ -13,8 +17,17 @@ type App struct {
 	HTTPServer *httpapp.App
 }
 
-func New(log *slog.Logger, router *chi.Mux, cnf *config.Config) *App {
-	grpcApp := grpcapp.New(log, cnf)
+func New(
+	log *slog.Logger,
+	router *chi.Mux,
+	cnf *config.Config,
+	issuer *jwtissuer.Issuer,
+	revocations authgrpc.RevocationChecker,
+	apiKeys authgrpc.APIKeyAuthenticator,
+	taskService *taskusecases.TaskService,
+	categoryService *categoryusecases.CategoryService,
+) *App {
+	grpcApp := grpcapp.New(log, cnf, issuer, revocations, apiKeys, taskService, categoryService)
 	httpApp := httpapp.New(log, router, cnf)
 
 	return &App{
@@ -6,8 +6,13 @@ import (
 	"google.golang.org/grpc/reflection"
 	"log/slog"
 	"net"
+	authgrpc "task-manager/internal/auth/transport/transport_grpc"
 	"task-manager/internal/config"
-	authgrpc "task-manager/internal/tasks_categories/transport/grpc"
+	taskgrpc "task-manager/internal/tasks/transport"
+	taskusecases "task-manager/internal/tasks/usecases"
+	categorygrpc "task-manager/internal/tasks_categories/transport/grpc"
+	categoryusecases "task-manager/internal/tasks_categories/usecases"
+	jwtissuer "task-manager/pkg/jwt"
 )
 
 type App struct {
@@ -16,9 +21,25 @@ type App struct {
 	port       int
 }
 
-func New(log *slog.Logger, cnf *config.Config) *App {
-	gRPCServer := grpc.NewServer()
-	authgrpc.Register(gRPCServer)
+func New(
+	log *slog.Logger,
+	cnf *config.Config,
+	issuer *jwtissuer.Issuer,
+	revocations authgrpc.RevocationChecker,
+	apiKeys authgrpc.APIKeyAuthenticator,
+	taskService *taskusecases.TaskService,
+	categoryService *categoryusecases.CategoryService,
+) *App {
+	authInterceptor := authgrpc.NewAuthInterceptor(log, issuer, revocations, apiKeys,
+		cnf.GRPCServer.PublicMethods, cnf.GRPCServer.AdminMethods,
+	)
+
+	gRPCServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authInterceptor.Unary()),
+		grpc.ChainStreamInterceptor(authInterceptor.Stream()),
+	)
+	taskgrpc.Register(gRPCServer, log, taskService)
+	categorygrpc.Register(gRPCServer, log, categoryService)
 	reflection.Register(gRPCServer)
 
 	return &App{
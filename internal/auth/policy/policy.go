@@ -0,0 +1,197 @@
+package policy
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"task-manager/internal/config"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+var (
+	ErrInvalidInput = errors.New("данные не соответствуют требованиям")
+)
+
+// FieldError Нарушение правила для одного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError Все нарушения разом, чтобы клиент мог показать их у полей формы
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidInput, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// Join Собирает нарушения в ошибку, nil если нарушений нет
+func Join(fields ...[]FieldError) error {
+	var all []FieldError
+	for _, f := range fields {
+		all = append(all, f...)
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: all}
+}
+
+// Policy Правила для логинов и паролей
+type Policy struct {
+	cnf       config.CredentialPolicy
+	denyList  map[string]struct{}
+	loginRune func(r rune) bool
+}
+
+func New(cnf config.CredentialPolicy) (*Policy, error) {
+	const op = "internal.auth.policy.New"
+
+	p := &Policy{cnf: cnf, denyList: make(map[string]struct{})}
+	if err := p.loadDenyList(strings.NewReader(commonPasswords)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cnf.PasswordDenyListFile != "" {
+		f, err := os.Open(cnf.PasswordDenyListFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		defer f.Close()
+
+		if err := p.loadDenyList(f); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, cnf.PasswordDenyListFile, err)
+		}
+	}
+
+	p.loginRune = func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(cnf.LoginAllowedSymbols, r)
+	}
+
+	return p, nil
+}
+
+// CheckLogin Длина и допустимые символы логина
+func (p *Policy) CheckLogin(login string) []FieldError {
+	var errs []FieldError
+
+	length := utf8.RuneCountInString(login)
+	if length < p.cnf.LoginMinLength {
+		errs = append(errs, FieldError{
+			Field: "login", Code: "too_short",
+			Message: fmt.Sprintf("логин должен быть не короче %d символов", p.cnf.LoginMinLength),
+		})
+	}
+	if length > p.cnf.LoginMaxLength {
+		errs = append(errs, FieldError{
+			Field: "login", Code: "too_long",
+			Message: fmt.Sprintf("логин должен быть не длиннее %d символов", p.cnf.LoginMaxLength),
+		})
+	}
+	if strings.IndexFunc(login, func(r rune) bool { return !p.loginRune(r) }) >= 0 {
+		errs = append(errs, FieldError{
+			Field: "login", Code: "invalid_chars",
+			Message: fmt.Sprintf("логин может содержать только буквы, цифры и символы %q", p.cnf.LoginAllowedSymbols),
+		})
+	}
+
+	return errs
+}
+
+// CheckPassword Длина, классы символов, список распространенных паролей
+// и совпадение с логином. field - имя поля в запросе
+func (p *Policy) CheckPassword(field, password, login string) []FieldError {
+	var errs []FieldError
+
+	if utf8.RuneCountInString(password) < p.cnf.PasswordMinLength {
+		errs = append(errs, FieldError{
+			Field: field, Code: "too_short",
+			Message: fmt.Sprintf("пароль должен быть не короче %d символов", p.cnf.PasswordMinLength),
+		})
+	}
+	// длина в байтах: так ее ограничивают алгоритмы хеширования
+	if len(password) > p.cnf.PasswordMaxLength {
+		errs = append(errs, FieldError{
+			Field: field, Code: "too_long",
+			Message: fmt.Sprintf("пароль должен быть не длиннее %d байт", p.cnf.PasswordMaxLength),
+		})
+	}
+	if classes := characterClasses(password); classes < p.cnf.PasswordMinClasses {
+		errs = append(errs, FieldError{
+			Field: field, Code: "missing_classes",
+			Message: fmt.Sprintf(
+				"пароль должен содержать символы хотя бы %d видов из: строчные буквы, заглавные буквы, цифры, прочие символы",
+				p.cnf.PasswordMinClasses,
+			),
+		})
+	}
+	if _, denied := p.denyList[strings.ToLower(password)]; denied {
+		errs = append(errs, FieldError{
+			Field: field, Code: "common_password",
+			Message: "пароль слишком распространен и легко подбирается",
+		})
+	}
+	if login != "" && strings.EqualFold(password, login) {
+		errs = append(errs, FieldError{
+			Field: field, Code: "same_as_login",
+			Message: "пароль не должен совпадать с логином",
+		})
+	}
+
+	return errs
+}
+
+func (p *Policy) loadDenyList(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.denyList[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"task-manager/internal/config"
+	"testing"
+)
+
+func newTestPolicy(t *testing.T) *Policy {
+	t.Helper()
+
+	p, err := New(config.CredentialPolicy{
+		LoginMinLength:      3,
+		LoginMaxLength:      32,
+		LoginAllowedSymbols: "._-",
+		PasswordMinLength:   8,
+		PasswordMaxLength:   72,
+		PasswordMinClasses:  3,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p
+}
+
+func codes(t *testing.T, errs []FieldError, field string) []string {
+	t.Helper()
+
+	var got []string
+	for _, e := range errs {
+		if e.Field != field {
+			t.Errorf("поле %q, ожидалось %q", e.Field, field)
+		}
+		got = append(got, e.Code)
+	}
+	return got
+}
+
+func TestCheckLogin(t *testing.T) {
+	p := newTestPolicy(t)
+
+	tests := []struct {
+		name  string
+		login string
+		want  []string
+	}{
+		{name: "латиница и разрешенные символы", login: "ivan.petrov_1"},
+		{name: "кириллица", login: "иван"},
+		// длина логина в символах, а не в байтах
+		{name: "многобайтовый на границе", login: strings.Repeat("я", 32)},
+		{name: "короткий", login: "iv", want: []string{"too_short"}},
+		{name: "длинный", login: strings.Repeat("a", 33), want: []string{"too_long"}},
+		{name: "пробел", login: "ivan petrov", want: []string{"invalid_chars"}},
+		{name: "короткий с запрещенным символом", login: "a@", want: []string{"too_short", "invalid_chars"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(t, p.CheckLogin(tt.login), "login")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckLogin(%q) = %v, ожидалось %v", tt.login, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	p := newTestPolicy(t)
+
+	// 34 заглавные Ж по 2 байта и 4 однобайтовых символа: ровно 72 байта
+	multiByte := strings.Repeat("Ж", 34) + "a1!x"
+
+	tests := []struct {
+		name     string
+		password string
+		login    string
+		want     []string
+	}{
+		{name: "надежный", password: "Correct-Horse7", login: "ivan"},
+		{name: "многобайтовый на границе в байтах", password: multiByte},
+		{name: "многобайтовый на байт длиннее", password: multiByte + "y", want: []string{"too_long"}},
+		{name: "короткий", password: "Ab1!", want: []string{"too_short"}},
+		{name: "длинный", password: strings.Repeat("Aa1-", 18) + "x", want: []string{"too_long"}},
+		{name: "один вид символов", password: "onlylowercase", want: []string{"missing_classes"}},
+		// список сравнивается без учета регистра
+		{name: "распространенный в другом регистре", password: "P@SSW0RD", want: []string{"common_password"}},
+		{name: "совпадает с логином", password: "ivan.petrov9", login: "Ivan.Petrov9", want: []string{"same_as_login"}},
+		{name: "без логина не сравнивается", password: "ivan.petrov9"},
+		{name: "несколько нарушений", password: "qwerty", want: []string{"too_short", "missing_classes", "common_password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := codes(t, p.CheckPassword("new_password", tt.password, tt.login), "new_password")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckPassword(%q) = %v, ожидалось %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCharacterClasses(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{password: "", want: 0},
+		{password: "abc", want: 1},
+		{password: "abcDEF", want: 2},
+		{password: "abcDEF123", want: 3},
+		{password: "abcDEF123!", want: 4},
+		{password: "пароль", want: 1},
+		{password: "Пароль 1", want: 4},
+	}
+	for _, tt := range tests {
+		if got := characterClasses(tt.password); got != tt.want {
+			t.Errorf("characterClasses(%q) = %d, ожидалось %d", tt.password, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API-ключ не найден")
+)
+
+// APIKey Персональный ключ для скриптов. Хранится только sha256 от ключа,
+// Prefix - его начало для отображения в списке
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var k APIKey
+	err := row.Scan(
+		&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, &k.Scopes,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r Repository) CreateAPIKey(ctx context.Context, k *APIKey) error {
+	const op = "auth.repo.CreateAPIKey"
+
+	stmt := `
+	INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at
+`
+	err := r.conn(ctx).QueryRow(ctx, stmt, k.UserID, k.Name, k.Prefix, k.KeyHash, k.Scopes, k.ExpiresAt).
+		Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// FindAPIKeyByHash Ищет ключ по хешу, в том числе отозванный или истекший
+func (r Repository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	const op = "auth.repo.FindAPIKeyByHash"
+
+	k, err := scanAPIKey(r.conn(ctx).QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return k, nil
+}
+
+// ListAPIKeys Ключи пользователя, новые первыми
+func (r Repository) ListAPIKeys(ctx context.Context, userID int) ([]APIKey, error) {
+	const op = "auth.repo.ListAPIKeys"
+
+	rows, err := r.conn(ctx).Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, wrapError(op, err)
+		}
+		keys = append(keys, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError(op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey Отзывает ключ пользователя. Чужой или уже отозванный ключ не найдется
+func (r Repository) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	const op = "auth.repo.RevokeAPIKey"
+
+	stmt := `
+	UPDATE api_keys
+	SET revoked_at = NOW()
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+	tag, err := r.conn(ctx).Exec(ctx, stmt, id, userID)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// TouchAPIKey Обновляет время последнего использования не чаще раза в interval,
+// чтобы частые запросы скриптов не писали в базу на каждый вызов
+func (r Repository) TouchAPIKey(ctx context.Context, id int, interval time.Duration) error {
+	const op = "auth.repo.TouchAPIKey"
+
+	stmt := `
+	UPDATE api_keys
+	SET last_used_at = NOW()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, id, interval.Seconds()); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuthEvent Запись журнала безопасности. UserID пуст, если вход был под
+// несуществующим логином. Строки не ссылаются на users, чтобы история
+// удаленных аккаунтов оставалась доступна администраторам
+type AuthEvent struct {
+	ID        int64          `json:"id"`
+	Type      string         `json:"type"`
+	UserID    *int           `json:"user_id,omitempty"`
+	Login     string         `json:"login,omitempty"`
+	IP        string         `json:"ip,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// AuthEventFilter Условия выборки журнала, пустые поля не ограничивают выборку
+type AuthEventFilter struct {
+	UserID *int
+	Types  []string
+	Login  string
+	IP     string
+	Since  *time.Time
+	Until  *time.Time
+	Limit  int
+	Offset int
+}
+
+func (r Repository) CreateAuthEvent(ctx context.Context, e *AuthEvent) error {
+	const op = "auth.repo.CreateAuthEvent"
+
+	details := e.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	stmt := `
+	INSERT INTO auth_events (type, user_id, login, ip, user_agent, request_id, details)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at
+`
+	err := r.conn(ctx).QueryRow(ctx, stmt, e.Type, e.UserID, e.Login, e.IP, e.UserAgent, e.RequestID, details).
+		Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// ListAuthEvents Страница журнала по фильтру, новые записи первыми, и общее число записей
+func (r Repository) ListAuthEvents(ctx context.Context, filter AuthEventFilter) ([]AuthEvent, int, error) {
+	const op = "auth.repo.ListAuthEvents"
+
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserID != nil {
+		where("user_id = $%d", *filter.UserID)
+	}
+	if len(filter.Types) > 0 {
+		where("type = ANY($%d)", filter.Types)
+	}
+	if filter.Login != "" {
+		where("lower(login) = lower($%d)", filter.Login)
+	}
+	if filter.IP != "" {
+		where("ip = $%d", filter.IP)
+	}
+	if filter.Since != nil {
+		where("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		where("created_at < $%d", *filter.Until)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.conn(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM auth_events `+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, wrapError(op, err)
+	}
+
+	stmt := fmt.Sprintf(`
+	SELECT id, type, user_id, login, ip, user_agent, request_id, details, created_at
+	FROM auth_events
+	%s
+	ORDER BY id DESC
+	LIMIT $%d OFFSET $%d
+`, whereClause, len(args)+1, len(args)+2)
+	rows, err := r.conn(ctx).Query(ctx, stmt, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, wrapError(op, err)
+	}
+	defer rows.Close()
+
+	events := make([]AuthEvent, 0, filter.Limit)
+	for rows.Next() {
+		var e AuthEvent
+		err := rows.Scan(&e.ID, &e.Type, &e.UserID, &e.Login, &e.IP, &e.UserAgent, &e.RequestID, &e.Details, &e.CreatedAt)
+		if err != nil {
+			return nil, 0, wrapError(op, err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, wrapError(op, err)
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"task-manager/pkg/clients/posgresql"
+	"time"
+)
+
+var (
+	ErrIdentityNotFound  = errors.New("внешняя учетная запись не привязана")
+	ErrOIDCStateNotFound = errors.New("состояние входа не найдено или истекло")
+	ErrIdentityExists    = errors.New("внешняя учетная запись уже привязана")
+)
+
+// UserIdentity Учетная запись внешнего провайдера, привязанная к пользователю
+type UserIdentity struct {
+	ID          int
+	UserID      int
+	Provider    string
+	Subject     string
+	Email       *string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
+
+// OIDCState Незавершенный вход через провайдера. Хранится хеш state,
+// nonce и code_verifier нужны для проверки ответа провайдера
+type OIDCState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+func (r Repository) CreateOIDCState(ctx context.Context, s *OIDCState) error {
+	const op = "auth.repo.CreateOIDCState"
+
+	stmt := `
+	INSERT INTO oidc_login_states (state_hash, nonce, code_verifier, expires_at)
+	VALUES ($1, $2, $3, $4)
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, s.StateHash, s.Nonce, s.CodeVerifier, s.ExpiresAt); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// ConsumeOIDCState Удаляет и возвращает состояние входа, истекшее не найдется
+func (r Repository) ConsumeOIDCState(ctx context.Context, stateHash string) (*OIDCState, error) {
+	const op = "auth.repo.ConsumeOIDCState"
+
+	stmt := `
+	DELETE FROM oidc_login_states
+	WHERE state_hash = $1 AND expires_at > NOW()
+	RETURNING state_hash, nonce, code_verifier, expires_at
+`
+	var s OIDCState
+	err := r.conn(ctx).QueryRow(ctx, stmt, stateHash).Scan(&s.StateHash, &s.Nonce, &s.CodeVerifier, &s.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrOIDCStateNotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return &s, nil
+}
+
+// CleanupOIDCStates Удаляет брошенные входы
+func (r Repository) CleanupOIDCStates(ctx context.Context) error {
+	const op = "auth.repo.CleanupOIDCStates"
+
+	if _, err := r.conn(ctx).Exec(ctx, `DELETE FROM oidc_login_states WHERE expires_at <= NOW()`); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func (r Repository) FindIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	const op = "auth.repo.FindIdentity"
+
+	stmt := `
+	SELECT id, user_id, provider, subject, email, created_at, last_login_at
+	FROM user_identities
+	WHERE provider = $1 AND subject = $2
+`
+	var i UserIdentity
+	err := r.conn(ctx).QueryRow(ctx, stmt, provider, subject).
+		Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrIdentityNotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return &i, nil
+}
+
+// LinkIdentity Привязывает внешнюю учетную запись к существующему пользователю
+func (r Repository) LinkIdentity(ctx context.Context, i *UserIdentity) error {
+	const op = "auth.repo.LinkIdentity"
+
+	if err := insertIdentity(ctx, r.dbClient, i); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity Создает пользователя вместе с внешней учетной записью одной транзакцией
+func (r Repository) CreateUserWithIdentity(ctx context.Context, u *User, i *UserIdentity) error {
+	const op = "auth.repo.CreateUserWithIdentity"
+
+	tx, err := r.conn(ctx).Begin(ctx)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := insertUser(ctx, tx, u); err != nil {
+		return wrapError(op, err)
+	}
+	i.UserID = u.ID
+	if err := insertIdentity(ctx, tx, i); err != nil {
+		return wrapError(op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// TouchIdentity Запоминает время входа и актуальный email у провайдера
+func (r Repository) TouchIdentity(ctx context.Context, id int, email *string) error {
+	const op = "auth.repo.TouchIdentity"
+
+	stmt := `UPDATE user_identities SET last_login_at = NOW(), email = $2 WHERE id = $1`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, id, email); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func insertIdentity(ctx context.Context, db posgresql.DBClient, i *UserIdentity) error {
+	stmt := `
+	INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+	VALUES ($1, $2, $3, $4, NOW())
+	RETURNING id, created_at
+`
+	return db.QueryRow(ctx, stmt, i.UserID, i.Provider, i.Subject, i.Email).Scan(&i.ID, &i.CreatedAt)
+}
@@ -0,0 +1,106 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttempts Счетчик неудачных входов по ключу (логин или IP). Методы Repository
+// хранят счетчики в Postgres для нескольких экземпляров сервиса, для одного
+// экземпляра достаточно MemoryLoginAttempts. Колонки времени TIMESTAMPTZ: время
+// из Go сравнивается в Go, и часовой пояс сервера не должен его сдвигать
+type LoginAttempts struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// ReserveLoginAttempt Атомарно учитывает попытку входа до проверки пароля и
+// возвращает счетчик без нее. Одновременные попытки получают разные значения,
+// поэтому не могут все пройти проверку по одному и тому же состоянию. Если с
+// прошлой неудачи прошло больше window, счет начинается заново
+func (r Repository) ReserveLoginAttempt(ctx context.Context, key string, now time.Time, window time.Duration) (LoginAttempts, error) {
+	const op = "auth.repo.ReserveLoginAttempt"
+
+	stmt := `
+	INSERT INTO login_attempts (key, failures, last_failure_at)
+	VALUES ($1, 1, $2)
+	ON CONFLICT (key) DO UPDATE
+	SET failures = CASE
+	        WHEN login_attempts.last_failure_at < $3 THEN 1
+	        ELSE login_attempts.failures + 1
+	    END,
+	    last_failure_at = CASE
+	        WHEN login_attempts.last_failure_at < $3 THEN EXCLUDED.last_failure_at
+	        ELSE login_attempts.last_failure_at
+	    END
+	RETURNING failures - 1, last_failure_at, locked_until
+`
+	var a LoginAttempts
+	err := r.conn(ctx).QueryRow(ctx, stmt, key, now, now.Add(-window)).Scan(&a.Failures, &a.LastFailureAt, &a.LockedUntil)
+	if err != nil {
+		return LoginAttempts{}, wrapError(op, err)
+	}
+
+	return a, nil
+}
+
+// ReleaseLoginAttempt Возвращает попытку, учтенную ReserveLoginAttempt, если она
+// не оказалась неудачной
+func (r Repository) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	const op = "auth.repo.ReleaseLoginAttempt"
+
+	if _, err := r.conn(ctx).Exec(ctx, `UPDATE login_attempts SET failures = GREATEST(failures - 1, 0) WHERE key = $1`, key); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// RegisterLoginFailure Отмечает время неудачи для попытки, уже учтенной
+// ReserveLoginAttempt: от него отсчитываются задержка и окно
+func (r Repository) RegisterLoginFailure(ctx context.Context, key string, now time.Time) error {
+	const op = "auth.repo.RegisterLoginFailure"
+
+	if _, err := r.conn(ctx).Exec(ctx, `UPDATE login_attempts SET last_failure_at = GREATEST(last_failure_at, $2) WHERE key = $1`, key, now); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// LockLogin Блокирует ключ до until и обнуляет счетчик: после блокировки отсчет начинается заново
+func (r Repository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	const op = "auth.repo.LockLogin"
+
+	if _, err := r.conn(ctx).Exec(ctx, `UPDATE login_attempts SET locked_until = $2, failures = 0 WHERE key = $1`, key, until); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func (r Repository) ResetLoginAttempts(ctx context.Context, key string) error {
+	const op = "auth.repo.ResetLoginAttempts"
+
+	if _, err := r.conn(ctx).Exec(ctx, `DELETE FROM login_attempts WHERE key = $1`, key); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// CleanupLoginAttempts Удаляет счетчики без свежих неудач и без действующей блокировки
+func (r Repository) CleanupLoginAttempts(ctx context.Context, olderThan time.Time) error {
+	const op = "auth.repo.CleanupLoginAttempts"
+
+	stmt := `
+	DELETE FROM login_attempts
+	WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < NOW())
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, olderThan); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLoginAttempts Хранилище счетчиков неудачных входов в памяти процесса.
+// Подходит для одного экземпляра сервиса, счетчики теряются при перезапуске
+type MemoryLoginAttempts struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempts
+}
+
+func NewMemoryLoginAttempts() *MemoryLoginAttempts {
+	return &MemoryLoginAttempts{attempts: make(map[string]LoginAttempts)}
+}
+
+func (m *MemoryLoginAttempts) ReserveLoginAttempt(_ context.Context, key string, now time.Time, window time.Duration) (LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.attempts[key]
+	if a.LastFailureAt.Before(now.Add(-window)) {
+		a.Failures = 0
+		a.LastFailureAt = now
+	}
+	reserved := a
+	a.Failures++
+	m.attempts[key] = a
+
+	return reserved, nil
+}
+
+func (m *MemoryLoginAttempts) ReleaseLoginAttempt(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.attempts[key]
+	if !ok {
+		return nil
+	}
+	a.Failures = max(a.Failures-1, 0)
+	m.attempts[key] = a
+
+	return nil
+}
+
+func (m *MemoryLoginAttempts) RegisterLoginFailure(_ context.Context, key string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.attempts[key]
+	if !ok {
+		return nil
+	}
+	if now.After(a.LastFailureAt) {
+		a.LastFailureAt = now
+	}
+	m.attempts[key] = a
+
+	return nil
+}
+
+func (m *MemoryLoginAttempts) LockLogin(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.attempts[key]
+	a.Failures = 0
+	a.LockedUntil = &until
+	m.attempts[key] = a
+
+	return nil
+}
+
+func (m *MemoryLoginAttempts) ResetLoginAttempts(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+
+	return nil
+}
+
+func (m *MemoryLoginAttempts) CleanupLoginAttempts(_ context.Context, olderThan time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, a := range m.attempts {
+		if a.LastFailureAt.Before(olderThan) && (a.LockedUntil == nil || a.LockedUntil.Before(now)) {
+			delete(m.attempts, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+var (
+	ErrMFANotFound = errors.New("второй фактор не настроен")
+)
+
+// MFA Настройки TOTP пользователя. До подтверждения первым кодом EnabledAt пуст
+type MFA struct {
+	UserID         int
+	Secret         string
+	EnabledAt      *time.Time
+	LastUsedStep   int64
+	FailedAttempts int
+	// LockedUntil до этого момента коды не проверяются
+	LockedUntil *time.Time
+}
+
+func (r Repository) FindMFA(ctx context.Context, userID int) (*MFA, error) {
+	const op = "auth.repo.FindMFA"
+
+	stmt := `
+	SELECT user_id, secret, enabled_at, last_used_step, failed_attempts, locked_until
+	FROM user_mfa
+	WHERE user_id = $1
+`
+	var m MFA
+	err := r.conn(ctx).QueryRow(ctx, stmt, userID).Scan(
+		&m.UserID, &m.Secret, &m.EnabledAt, &m.LastUsedStep, &m.FailedAttempts, &m.LockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return &m, nil
+}
+
+// SaveMFASecret Начинает настройку заново с новым секретом. Уже включенный
+// второй фактор не перезаписывается
+func (r Repository) SaveMFASecret(ctx context.Context, userID int, secret string) error {
+	const op = "auth.repo.SaveMFASecret"
+
+	stmt := `
+	INSERT INTO user_mfa (user_id, secret)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, last_used_step = 0, failed_attempts = 0, created_at = NOW()
+	WHERE user_mfa.enabled_at IS NULL
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, userID, secret); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// EnableMFA Включает второй фактор и сохраняет хеши кодов восстановления одной транзакцией
+func (r Repository) EnableMFA(ctx context.Context, userID int, codeHashes []string) error {
+	const op = "auth.repo.EnableMFA"
+
+	tx, err := r.conn(ctx).Begin(ctx)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE user_mfa SET enabled_at = NOW() WHERE user_id = $1`, userID); err != nil {
+		return wrapError(op, err)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return wrapError(op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes Заменяет все коды восстановления новыми
+func (r Repository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	const op = "auth.repo.ReplaceRecoveryCodes"
+
+	tx, err := r.conn(ctx).Begin(ctx)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return wrapError(op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func (r Repository) DeleteMFA(ctx context.Context, userID int) error {
+	const op = "auth.repo.DeleteMFA"
+
+	if _, err := r.conn(ctx).Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return wrapError(op, err)
+	}
+	if _, err := r.conn(ctx).Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// UseMFAStep Запоминает шаг принятого кода. Возвращает false, если код этого
+// или более позднего шага уже использовался, что защищает от повторного предъявления
+func (r Repository) UseMFAStep(ctx context.Context, userID int, step int64) (bool, error) {
+	const op = "auth.repo.UseMFAStep"
+
+	stmt := `
+	UPDATE user_mfa
+	SET last_used_step = $2
+	WHERE user_id = $1 AND last_used_step < $2
+`
+	tag, err := r.conn(ctx).Exec(ctx, stmt, userID, step)
+	if err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode Помечает код восстановления использованным, false - кода нет или он уже использован
+func (r Repository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	const op = "auth.repo.UseRecoveryCode"
+
+	stmt := `
+	UPDATE mfa_recovery_codes
+	SET used_at = NOW()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+	tag, err := r.conn(ctx).Exec(ctx, stmt, userID, codeHash)
+	if err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// RegisterMFAFailure Увеличивает счетчик неверных кодов и возвращает его новое
+// значение. Начиная с maxAttempts каждая неудача блокирует проверку на lockout
+func (r Repository) RegisterMFAFailure(ctx context.Context, userID int, maxAttempts int, lockout time.Duration) (int, error) {
+	const op = "auth.repo.RegisterMFAFailure"
+
+	stmt := `
+	UPDATE user_mfa
+	SET failed_attempts = failed_attempts + 1,
+	    locked_until = CASE
+	        WHEN failed_attempts + 1 >= $2 THEN NOW() + make_interval(secs => $3)
+	        ELSE locked_until
+	    END
+	WHERE user_id = $1
+	RETURNING failed_attempts
+`
+	var attempts int
+	if err := r.conn(ctx).QueryRow(ctx, stmt, userID, maxAttempts, lockout.Seconds()).Scan(&attempts); err != nil {
+		return 0, wrapError(op, err)
+	}
+
+	return attempts, nil
+}
+
+func (r Repository) ResetMFAFailures(ctx context.Context, userID int) error {
+	const op = "auth.repo.ResetMFAFailures"
+
+	if _, err := r.conn(ctx).Exec(ctx, `UPDATE user_mfa SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1`, userID); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(ctx, `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,10 +2,24 @@ package repo
 
 import "time"
 
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID           int       `json:"ID"`
-	Login        string    `json:"login"`
-	PasswordHash string    `json:"password_hash"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int     `json:"ID"`
+	Login        string  `json:"login"`
+	Email        *string `json:"email,omitempty"`
+	PasswordHash string  `json:"password_hash"`
+	// VerifiedAt время подтверждения email, nil - не подтвержден
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	Role       string     `json:"role"`
+	// DisabledAt время блокировки администратором, nil - аккаунт активен
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// PasswordResetRequired вход по паролю запрещен до сброса пароля по ссылке
+	PasswordResetRequired bool      `json:"password_reset_required"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// Назначения одноразовых токенов
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+var (
+	ErrOneTimeTokenNotFound = errors.New("одноразовый токен не найден, истек или уже использован")
+)
+
+type OneTimeToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+// CreateOneTimeToken Сохраняет новый токен, аннулируя неиспользованные токены
+// того же назначения: действительна только последняя выданная ссылка
+func (r Repository) CreateOneTimeToken(ctx context.Context, t *OneTimeToken) error {
+	const op = "auth.repo.CreateOneTimeToken"
+
+	_, err := r.conn(ctx).Exec(ctx, `
+	DELETE FROM one_time_tokens
+	WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+`, t.UserID, t.Purpose)
+	if err != nil {
+		return wrapError(op, err)
+	}
+
+	stmt := `
+		INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+		`
+	if err := r.conn(ctx).QueryRow(ctx, stmt, t.UserID, t.Purpose, t.TokenHash, t.ExpiresAt).Scan(&t.ID); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// FindOneTimeToken Возвращает действующий токен, не помечая его использованным
+func (r Repository) FindOneTimeToken(ctx context.Context, tokenHash string, purpose string) (*OneTimeToken, error) {
+	const op = "auth.repo.FindOneTimeToken"
+
+	stmt := `
+	SELECT id, user_id, purpose, token_hash, expires_at
+	FROM one_time_tokens
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+`
+	var t OneTimeToken
+	err := r.conn(ctx).QueryRow(ctx, stmt, tokenHash, purpose).Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrOneTimeTokenNotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return &t, nil
+}
+
+// ConsumeOneTimeToken Атомарно помечает токен использованным и возвращает его.
+// Истекший, чужого назначения или уже использованный токен не найдется
+func (r Repository) ConsumeOneTimeToken(ctx context.Context, tokenHash string, purpose string) (*OneTimeToken, error) {
+	const op = "auth.repo.ConsumeOneTimeToken"
+
+	stmt := `
+	UPDATE one_time_tokens
+	SET used_at = NOW()
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING id, user_id, purpose, token_hash, expires_at
+`
+	var t OneTimeToken
+	err := r.conn(ctx).QueryRow(ctx, stmt, tokenHash, purpose).Scan(&t.ID, &t.UserID, &t.Purpose, &t.TokenHash, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrOneTimeTokenNotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh-токен не найден")
+)
+
+type RefreshToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (r Repository) CreateRefreshToken(ctx context.Context, t *RefreshToken) error {
+	const op = "auth.repo.CreateRefreshToken"
+
+	stmt := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+		`
+	err := r.conn(ctx).QueryRow(ctx, stmt, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func (r Repository) FindRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	const op = "auth.repo.FindRefreshToken"
+
+	stmt := `
+	SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+	FROM refresh_tokens
+	WHERE token_hash = $1
+`
+	var t RefreshToken
+	err := r.conn(ctx).QueryRow(ctx, stmt, tokenHash).Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.UsedAt, &t.RevokedAt, &t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, ErrRefreshTokenNotFound)
+		}
+		return nil, wrapError(op, err)
+	}
+
+	return &t, nil
+}
+
+// MarkRefreshTokenUsed Помечает токен использованным. Возвращает false, если токен
+// уже был использован или отозван - это признак повторного предъявления
+func (r Repository) MarkRefreshTokenUsed(ctx context.Context, id int) (bool, error) {
+	const op = "auth.repo.MarkRefreshTokenUsed"
+
+	stmt := `
+	UPDATE refresh_tokens
+	SET used_at = NOW()
+	WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+`
+	pgTag, err := r.conn(ctx).Exec(ctx, stmt, id)
+	if err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return pgTag.RowsAffected() == 1, nil
+}
+
+// RevokeRefreshFamily Отзывает все токены, выпущенные в рамках одного входа
+func (r Repository) RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	const op = "auth.repo.RevokeRefreshFamily"
+
+	stmt := `
+	UPDATE refresh_tokens
+	SET revoked_at = NOW()
+	WHERE family_id = $1 AND revoked_at IS NULL
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, familyID); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 
 var (
 	ErrUserExists   = errors.New("пользователь с таким логином уже существует")
+	ErrEmailExists  = errors.New("пользователь с таким email уже существует")
 	ErrUserNotFound = errors.New("пользователь с таким логином не найден")
 )
 
@@ -23,6 +24,12 @@ func wrapError(op string, err error) error {
 	case errors.As(err, &pgErr):
 		switch pgErr.Code {
 		case "23505": // Unique constraint violation
+			switch pgErr.ConstraintName {
+			case "users_email_key":
+				return fmt.Errorf("%s: %w", op, ErrEmailExists)
+			case "user_identities_provider_subject_key":
+				return fmt.Errorf("%s: %w", op, ErrIdentityExists)
+			}
 			return fmt.Errorf("%s: %w", op, ErrUserExists)
 		default:
 			return fmt.Errorf("%s: %s: %w", op, pgErr.Code, err)
@@ -36,70 +43,201 @@ type Repository struct {
 	dbClient posgresql.DBClient
 }
 
+// conn Соединение для запроса: транзакция, если она открыта в ctx, иначе пул
+func (r Repository) conn(ctx context.Context) posgresql.DBClient {
+	return posgresql.Conn(ctx, r.dbClient)
+}
+
+const userColumns = `id, login, email, password_hash, verified_at, role, disabled_at, password_reset_required, created_at, updated_at`
+
+func scanUser(row pgx.Row) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID, &user.Login, &user.Email, &user.PasswordHash, &user.VerifiedAt,
+		&user.Role, &user.DisabledAt, &user.PasswordResetRequired, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r Repository) Create(ctx context.Context, u *User) error {
 	const op = "auth.repo.Create"
+
+	if err := insertUser(ctx, r.conn(ctx), u); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+func insertUser(ctx context.Context, db posgresql.DBClient, u *User) error {
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+
 	stmt := `
-		INSERT INTO users (login, password_hash) 
-		VALUES($1, $2)
+		INSERT INTO users (login, email, password_hash, verified_at, role) 
+		VALUES($1, $2, $3, $4, $5)
 		RETURNING id
 		`
-	err := r.dbClient.QueryRow(ctx, stmt, u.Login, u.PasswordHash).Scan(&u.ID)
+	return db.QueryRow(ctx, stmt, u.Login, u.Email, u.PasswordHash, u.VerifiedAt, u.Role).Scan(&u.ID)
+}
+
+// FindAll Страница пользователей по возрастанию id и общее их число
+func (r Repository) FindAll(ctx context.Context, limit, offset int) ([]User, int, error) {
+	const op = "auth.repo.FindAll"
+
+	var total int
+	if err := r.conn(ctx).QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&total); err != nil {
+		return nil, 0, wrapError(op, err)
+	}
+
+	stmt := `
+	SELECT ` + userColumns + `
+	FROM users
+	ORDER BY id
+	LIMIT $1 OFFSET $2
+`
+	rows, err := r.conn(ctx).Query(ctx, stmt, limit, offset)
 	if err != nil {
-		return wrapError(op, err)
+		return nil, 0, wrapError(op, err)
 	}
+	defer rows.Close()
 
-	return nil
+	users := make([]User, 0, limit)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, wrapError(op, err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, wrapError(op, err)
+	}
+
+	return users, total, nil
 }
 
-func (r Repository) FindAll(ctx context.Context) ([]User, error) {
-	//TODO implement me
-	panic("implement me")
+// HasAdmin Есть ли хотя бы один администратор
+func (r Repository) HasAdmin(ctx context.Context) (bool, error) {
+	const op = "auth.repo.HasAdmin"
+
+	var exists bool
+	err := r.conn(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE role = $1)`, RoleAdmin).Scan(&exists)
+	if err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return exists, nil
 }
 
 func (r Repository) FindOne(ctx context.Context, login string) (*User, error) {
 	const op = "auth.repo.FindOne"
 
 	stmt := `
-	SELECT id, login, password_hash, created_at, updated_at
+	SELECT ` + userColumns + `
 	FROM users 
 	WHERE login = $1
 `
-	var user User
-
-	err := r.dbClient.QueryRow(ctx, stmt, login).Scan(
-		&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(r.conn(ctx).QueryRow(ctx, stmt, login))
 	if err != nil {
 		return nil, wrapError(op, err)
 	}
 
-	return &user, nil
+	return user, nil
 
 }
 
+func (r Repository) FindOneByEmail(ctx context.Context, email string) (*User, error) {
+	const op = "auth.repo.FindOneByEmail"
+
+	stmt := `
+	SELECT ` + userColumns + `
+	FROM users 
+	WHERE email = $1
+`
+	user, err := scanUser(r.conn(ctx).QueryRow(ctx, stmt, email))
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
+
+	return user, nil
+}
+
 func (r Repository) FindOneByID(ctx context.Context, id int) (*User, error) {
 	const op = "auth.repo.FindOneByID"
 
 	stmt := `
-	SELECT id, login, password_hash, created_at, updated_at
+	SELECT ` + userColumns + `
 	FROM users 
 	WHERE id = $1
 `
-	var user User
-	err := r.dbClient.QueryRow(ctx, stmt, id).Scan(
-		&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt,
-	)
+	user, err := scanUser(r.conn(ctx).QueryRow(ctx, stmt, id))
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
 
+	return user, nil
+}
+
+// FindOneByIDForUpdate Как FindOneByID, но блокирует строку до конца транзакции
+// из ctx, чтобы Update не затер параллельные изменения
+func (r Repository) FindOneByIDForUpdate(ctx context.Context, id int) (*User, error) {
+	const op = "auth.repo.FindOneByIDForUpdate"
+
+	stmt := `
+	SELECT ` + userColumns + `
+	FROM users 
+	WHERE id = $1
+	FOR UPDATE
+`
+	user, err := scanUser(r.conn(ctx).QueryRow(ctx, stmt, id))
 	if err != nil {
 		return nil, wrapError(op, err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 func (r Repository) Update(ctx context.Context, u *User) error {
-	//TODO implement me
-	panic("implement me")
+	const op = "auth.repo.Update"
+
+	stmt := `
+	UPDATE users
+	SET login = $1, email = $2, password_hash = $3, verified_at = $4,
+	    role = $5, disabled_at = $6, password_reset_required = $7, updated_at = NOW()
+	WHERE id = $8
+	RETURNING updated_at
+`
+	err := r.conn(ctx).QueryRow(ctx, stmt,
+		u.Login, u.Email, u.PasswordHash, u.VerifiedAt, u.Role, u.DisabledAt, u.PasswordResetRequired, u.ID,
+	).Scan(&u.UpdatedAt)
+	if err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// UpdatePasswordHash Заменяет хеш пароля, только если он не менялся с момента
+// чтения. false - пароль успели сменить или пользователя удалили
+func (r Repository) UpdatePasswordHash(ctx context.Context, id int, oldHash, newHash string) (bool, error) {
+	const op = "auth.repo.UpdatePasswordHash"
+
+	stmt := `
+	UPDATE users
+	SET password_hash = $3, updated_at = NOW()
+	WHERE id = $1 AND password_hash = $2
+`
+	tag, err := r.conn(ctx).Exec(ctx, stmt, id, oldHash, newHash)
+	if err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
 }
 
 func (r Repository) Delete(ctx context.Context, id int) error {
@@ -110,7 +248,7 @@ func (r Repository) Delete(ctx context.Context, id int) error {
 	FROM users 
 	WHERE id = $1
 `
-	pgTag, err := r.dbClient.Exec(ctx, query, id)
+	pgTag, err := r.conn(ctx).Exec(ctx, query, id)
 	if err != nil {
 		return wrapError(op, err)
 	}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// RevokeToken Заносит access-токен в список отозванных до момента его истечения
+func (r Repository) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	const op = "auth.repo.RevokeToken"
+
+	stmt := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+		`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, jti, userID, expiresAt); err != nil {
+		return wrapError(op, err)
+	}
+
+	// истекшие токены и так не пройдут проверку, хранить их незачем
+	if _, err := r.conn(ctx).Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < NOW()`); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// RevokeAllUserTokens Отзывает все access-токены пользователя с iat раньше cutoff.
+// Отсечка только сдвигается вперед
+func (r Repository) RevokeAllUserTokens(ctx context.Context, userID int, cutoff time.Time) error {
+	const op = "auth.repo.RevokeAllUserTokens"
+
+	stmt := `
+		INSERT INTO user_token_cutoffs (user_id, revoked_before)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET revoked_before = GREATEST(user_token_cutoffs.revoked_before, EXCLUDED.revoked_before)
+		`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, userID, cutoff); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens Отзывает все refresh-токены пользователя
+func (r Repository) RevokeUserRefreshTokens(ctx context.Context, userID int) error {
+	const op = "auth.repo.RevokeUserRefreshTokens"
+
+	stmt := `
+	UPDATE refresh_tokens
+	SET revoked_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, userID); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// UserTokenCutoff Отсечка отзыва токенов пользователя, нулевое время - отсечки нет
+func (r Repository) UserTokenCutoff(ctx context.Context, userID int) (time.Time, error) {
+	const op = "auth.repo.UserTokenCutoff"
+
+	var cutoff time.Time
+	err := r.conn(ctx).QueryRow(ctx, `SELECT revoked_before FROM user_token_cutoffs WHERE user_id = $1`, userID).Scan(&cutoff)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, wrapError(op, err)
+	}
+
+	return cutoff, nil
+}
+
+// IsTokenRevoked Проверяет, отозван ли токен лично или отсечкой по пользователю
+func (r Repository) IsTokenRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error) {
+	const op = "auth.repo.IsTokenRevoked"
+
+	stmt := `
+	SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)
+	    OR EXISTS(SELECT 1 FROM user_token_cutoffs WHERE user_id = $2 AND revoked_before > $3)
+`
+	var revoked bool
+	if err := r.conn(ctx).QueryRow(ctx, stmt, jti, userID, issuedAt).Scan(&revoked); err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("сессия не найдена")
+)
+
+// Session Один вход пользователя. ID совпадает с семейством refresh-токенов
+// и claim sid в access-токене, AccessJTI - jti последнего выданного access-токена
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int        `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	AccessJTI  string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+const sessionColumns = `id, user_id, user_agent, ip, access_jti, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row pgx.Row) (*Session, error) {
+	var s Session
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.AccessJTI,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r Repository) CreateSession(ctx context.Context, s *Session) error {
+	const op = "auth.repo.CreateSession"
+
+	stmt := `
+	INSERT INTO sessions (id, user_id, user_agent, ip, access_jti, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING created_at, last_seen_at
+`
+	err := r.conn(ctx).QueryRow(ctx, stmt, s.ID, s.UserID, s.UserAgent, s.IP, s.AccessJTI, s.ExpiresAt).
+		Scan(&s.CreatedAt, &s.LastSeenAt)
+	if err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// RenewSession Привязывает сессию к новому access-токену после обновления пары
+func (r Repository) RenewSession(ctx context.Context, id, accessJTI string, expiresAt time.Time) error {
+	const op = "auth.repo.RenewSession"
+
+	stmt := `
+	UPDATE sessions
+	SET access_jti = $2, expires_at = $3, last_seen_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, id, accessJTI, expiresAt); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// ListSessions Действующие сессии пользователя, последние активные первыми
+func (r Repository) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	const op = "auth.repo.ListSessions"
+
+	stmt := `SELECT ` + sessionColumns + ` FROM sessions
+	WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	ORDER BY last_seen_at DESC`
+	rows, err := r.conn(ctx).Query(ctx, stmt, userID)
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]Session, 0)
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, wrapError(op, err)
+		}
+		sessions = append(sessions, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError(op, err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession Отзывает сессию пользователя. Чужая или уже отозванная сессия не найдется
+func (r Repository) RevokeSession(ctx context.Context, userID int, id string) error {
+	const op = "auth.repo.RevokeSession"
+
+	stmt := `
+	UPDATE sessions
+	SET revoked_at = NOW()
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+	tag, err := r.conn(ctx).Exec(ctx, stmt, id, userID)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrSessionNotFound)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions Отзывает все сессии пользователя
+func (r Repository) RevokeUserSessions(ctx context.Context, userID int) error {
+	const op = "auth.repo.RevokeUserSessions"
+
+	stmt := `
+	UPDATE sessions
+	SET revoked_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL
+`
+	if _, err := r.conn(ctx).Exec(ctx, stmt, userID); err != nil {
+		return wrapError(op, err)
+	}
+
+	return nil
+}
+
+// TouchSession Отмечает активность сессии не чаще раза в interval и сообщает,
+// не отозвана ли она. Токены, выпущенные до появления сессий, строки не имеют
+// и считаются действующими
+func (r Repository) TouchSession(ctx context.Context, id string, interval time.Duration) (bool, error) {
+	const op = "auth.repo.TouchSession"
+
+	stmt := `
+	WITH touched AS (
+	    UPDATE sessions
+	    SET last_seen_at = NOW()
+	    WHERE id = $1 AND revoked_at IS NULL AND last_seen_at < NOW() - make_interval(secs => $2)
+	)
+	SELECT NOT EXISTS(SELECT 1 FROM sessions WHERE id = $1 AND revoked_at IS NOT NULL)
+`
+	var active bool
+	if err := r.conn(ctx).QueryRow(ctx, stmt, id, interval.Seconds()).Scan(&active); err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return active, nil
+}
@@ -0,0 +1,253 @@
+package transport_grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	jwtissuer "task-manager/pkg/jwt"
+)
+
+type userIDKey struct{}
+
+type roleKey struct{}
+
+// ContextWithUserID Кладет идентификатор аутентифицированного пользователя в контекст
+func ContextWithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext Возвращает идентификатор пользователя, положенный интерцептором
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(int)
+	return userID, ok
+}
+
+// ContextWithRole Кладет роль аутентифицированного пользователя в контекст
+func ContextWithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// RoleFromContext Возвращает роль пользователя, положенную интерцептором
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(roleKey{}).(string)
+	return role, ok
+}
+
+// RevocationChecker Проверка токена по списку отзыва
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, token jwt.Token) (bool, error)
+}
+
+// APIKeyAuthenticator Проверка API-ключа из метаданных "authorization: ApiKey K"
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*usecases.APIKeyPrincipal, error)
+}
+
+// AuthInterceptor Проверяет токены, выданные LoginHandler, для всех методов,
+// кроме перечисленных в publicMethods. Методы из adminMethods доступны только администраторам
+type AuthInterceptor struct {
+	log           *slog.Logger
+	issuer        *jwtissuer.Issuer
+	revocations   RevocationChecker
+	apiKeys       APIKeyAuthenticator
+	publicMethods []string
+	adminMethods  []string
+}
+
+// NewAuthInterceptor publicMethods и adminMethods - полные имена методов ("/pkg.Service/Method")
+// или префиксы сервисов, заканчивающиеся на "/" ("/grpc.health.v1.Health/")
+func NewAuthInterceptor(
+	log *slog.Logger,
+	issuer *jwtissuer.Issuer,
+	revocations RevocationChecker,
+	apiKeys APIKeyAuthenticator,
+	publicMethods []string,
+	adminMethods []string,
+) *AuthInterceptor {
+	return &AuthInterceptor{
+		log:           log,
+		issuer:        issuer,
+		revocations:   revocations,
+		apiKeys:       apiKeys,
+		publicMethods: publicMethods,
+		adminMethods:  adminMethods,
+	}
+}
+
+func (i *AuthInterceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if matchMethod(i.publicMethods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (i *AuthInterceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if matchMethod(i.publicMethods, info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (i *AuthInterceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	const op = "internal.auth.grpc.authenticate"
+
+	log := i.log.With(slog.String("op", op), slog.String("method", method))
+
+	scheme, credentials := credentialsFromMetadata(ctx)
+	if strings.EqualFold(scheme, "ApiKey") && credentials != "" {
+		return i.authenticateAPIKey(ctx, log, method, credentials)
+	}
+
+	tokenString := ""
+	if strings.EqualFold(scheme, "Bearer") {
+		tokenString = credentials
+	}
+	if tokenString == "" {
+		return nil, status.Error(codes.Unauthenticated, "токен не передан")
+	}
+
+	token, err := i.issuer.Parse(tokenString)
+	if err != nil {
+		log.Info("Невалидный токен", slog.String("reason", err.Error()))
+		return nil, status.Error(codes.Unauthenticated, "невалидный токен")
+	}
+	if !usecases.IsAccessToken(token) {
+		return nil, status.Error(codes.Unauthenticated, "вход не завершен")
+	}
+
+	revoked, err := i.revocations.IsRevoked(ctx, token)
+	if err != nil {
+		log.Error("Ошибка проверки отзыва токена", slog.String("reason", err.Error()))
+		return nil, status.Error(codes.Internal, "внутренняя ошибка")
+	}
+	if revoked {
+		return nil, status.Error(codes.Unauthenticated, "токен отозван")
+	}
+
+	claims, err := token.AsMap(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "невалидный токен")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return nil, status.Error(codes.Unauthenticated, "в токене нет user_id")
+	}
+
+	role := usecases.RoleFromToken(token)
+	if matchMethod(i.adminMethods, method) && role != repo.RoleAdmin {
+		log.Info("Недостаточно прав для метода", slog.Int("user_id", int(userID)), slog.String("role", role))
+		return nil, status.Error(codes.PermissionDenied, "недостаточно прав")
+	}
+
+	ctx = jwtauth.NewContext(ctx, token, nil)
+	ctx = ContextWithRole(ctx, role)
+	return ContextWithUserID(ctx, int(userID)), nil
+}
+
+// authenticateAPIKey Проверяет API-ключ. Методам чтения нужна область read, остальным - write,
+// методам администратора дополнительно admin
+func (i *AuthInterceptor) authenticateAPIKey(ctx context.Context, log *slog.Logger, method, rawKey string) (context.Context, error) {
+	principal, err := i.apiKeys.Authenticate(ctx, rawKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidAPIKey):
+			return nil, status.Error(codes.Unauthenticated, "невалидный API-ключ")
+		case errors.Is(err, usecases.ErrAccountDisabled):
+			return nil, status.Error(codes.PermissionDenied, "аккаунт заблокирован")
+		default:
+			log.Error("Ошибка проверки API-ключа", slog.String("reason", err.Error()))
+			return nil, status.Error(codes.Internal, "внутренняя ошибка")
+		}
+	}
+
+	if !principal.HasScope(scopeForMethod(method)) {
+		log.Info("Недостаточно прав API-ключа", slog.Int("key_id", principal.KeyID))
+		return nil, status.Error(codes.PermissionDenied, "у API-ключа нет прав на этот метод")
+	}
+	if matchMethod(i.adminMethods, method) && (principal.Role != repo.RoleAdmin || !principal.HasScope(usecases.ScopeAdmin)) {
+		return nil, status.Error(codes.PermissionDenied, "недостаточно прав")
+	}
+
+	token, err := principal.Token()
+	if err != nil {
+		log.Error("Ошибка создания токена API-ключа", slog.String("reason", err.Error()))
+		return nil, status.Error(codes.Internal, "внутренняя ошибка")
+	}
+
+	ctx = jwtauth.NewContext(ctx, token, nil)
+	ctx = ContextWithRole(ctx, principal.Role)
+	return ContextWithUserID(ctx, principal.UserID), nil
+}
+
+// scopeForMethod Методы Read*, Get* и List* только читают
+func scopeForMethod(fullMethod string) string {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range []string{"Read", "Get", "List"} {
+		if strings.HasPrefix(name, prefix) {
+			return usecases.ScopeRead
+		}
+	}
+	return usecases.ScopeWrite
+}
+
+// matchMethod Совпадает ли метод с одним из имен или префиксов сервисов
+func matchMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if method == m || (strings.HasSuffix(m, "/") && strings.HasPrefix(method, m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialsFromMetadata Разбирает метаданные "authorization: <схема> <значение>",
+// схема - Bearer для токена или ApiKey для API-ключа
+func credentialsFromMetadata(ctx context.Context) (string, string) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ""
+	}
+
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", ""
+	}
+
+	scheme, credentials, _ := strings.Cut(values[0], " ")
+	return scheme, strings.TrimSpace(credentials)
+}
+
+// authenticatedStream Подменяет контекст потока на контекст с пользователем
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,193 @@
+package transport_http
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// AdminListUsersHandler эндпоинт списка пользователей, страница задается ?limit=&offset=
+func AdminListUsersHandler(log *slog.Logger, service *usecases.AdminService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.admin.AdminListUsersHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, errLimit := queryInt(r, "limit")
+		offset, errOffset := queryInt(r, "offset")
+		if errLimit != nil || errOffset != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "limit и offset должны быть числами"})
+			return
+		}
+
+		page, err := service.ListUsers(r.Context(), limit, offset)
+		if err != nil {
+			renderAdminError(w, r, log, err)
+			return
+		}
+
+		users := make([]UserView, 0, len(page.Users))
+		for i := range page.Users {
+			users = append(users, *newUserView(&page.Users[i]))
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, UsersPageResponse{
+			Status: "ok",
+			Users:  users,
+			Total:  page.Total,
+			Limit:  page.Limit,
+			Offset: page.Offset,
+		})
+	}
+}
+
+// AdminGetUserHandler эндпоинт просмотра пользователя
+func AdminGetUserHandler(log *slog.Logger, service *usecases.AdminService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.admin.AdminGetUserHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := service.GetUser(r.Context(), userID)
+		if err != nil {
+			renderAdminError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", User: newUserView(user)})
+	}
+}
+
+// AdminDisableUserHandler эндпоинт блокировки аккаунта
+func AdminDisableUserHandler(log *slog.Logger, service *usecases.AdminService) http.HandlerFunc {
+	return adminUserAction(log, "internal.handlers.rest.user.admin.AdminDisableUserHandler", service.DisableUser)
+}
+
+// AdminEnableUserHandler эндпоинт снятия блокировки аккаунта
+func AdminEnableUserHandler(log *slog.Logger, service *usecases.AdminService) http.HandlerFunc {
+	return adminUserAction(log, "internal.handlers.rest.user.admin.AdminEnableUserHandler", service.EnableUser)
+}
+
+// AdminForcePasswordResetHandler эндпоинт принудительного сброса пароля
+func AdminForcePasswordResetHandler(log *slog.Logger, service *usecases.AdminService) http.HandlerFunc {
+	return adminUserAction(log, "internal.handlers.rest.user.admin.AdminForcePasswordResetHandler", service.ForcePasswordReset)
+}
+
+// AdminSetRoleHandler эндпоинт смены роли пользователя
+func AdminSetRoleHandler(log *slog.Logger, service *usecases.AdminService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.admin.AdminSetRoleHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		var req RequestSetRole
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		user, err := service.SetRole(r.Context(), userIDFromClaims(r), userID, req.Role)
+		if err != nil {
+			renderAdminError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", User: newUserView(user)})
+	}
+}
+
+// adminUserAction Обработчик действия администратора над пользователем из пути запроса
+func adminUserAction(
+	log *slog.Logger,
+	op string,
+	action func(ctx context.Context, adminID, userID int) (*repo.User, error),
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := action(r.Context(), userIDFromClaims(r), userID)
+		if err != nil {
+			renderAdminError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", User: newUserView(user)})
+	}
+}
+
+func renderAdminError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, repo.ErrUserNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, Response{Status: "error", Error: "Пользователь не найден"})
+	case errors.Is(err, usecases.ErrSelfModification):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{Status: "error", Error: "Нельзя заблокировать себя или снять с себя роль администратора"})
+	case errors.Is(err, usecases.ErrUserHasNoEmail):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{Status: "error", Error: "У пользователя нет email для ссылки сброса пароля"})
+	case errors.Is(err, usecases.ErrInvalidRole):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Неизвестная роль"})
+	default:
+		log.Error("Ошибка администрирования пользователя", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+	}
+}
+
+// userIDFromPath Достает идентификатор пользователя из пути, при ошибке сам отвечает 400
+func userIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "некорректный идентификатор пользователя"})
+		return 0, false
+	}
+	return id, true
+}
+
+// queryInt Необязательный числовой параметр запроса, отсутствующий считается нулем
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
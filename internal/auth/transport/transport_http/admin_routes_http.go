@@ -0,0 +1,30 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/usecases"
+)
+
+// AdminRoutes Маршруты администрирования пользователей. adminOnly проверяет
+// токен и роль администратора
+func AdminRoutes(
+	r *chi.Mux,
+	log *slog.Logger,
+	adminService *usecases.AdminService,
+	auditService *usecases.AuditService,
+	adminOnly func(http.Handler) http.Handler,
+) {
+	r.Group(func(r chi.Router) {
+		r.Use(adminOnly)
+
+		r.Get("/admin/users", AdminListUsersHandler(log, adminService))
+		r.Get("/admin/users/{id}", AdminGetUserHandler(log, adminService))
+		r.Post("/admin/users/{id}/disable", AdminDisableUserHandler(log, adminService))
+		r.Post("/admin/users/{id}/enable", AdminEnableUserHandler(log, adminService))
+		r.Post("/admin/users/{id}/password-reset", AdminForcePasswordResetHandler(log, adminService))
+		r.Post("/admin/users/{id}/role", AdminSetRoleHandler(log, adminService))
+		r.Get("/admin/security-events", AdminSecurityEventsHandler(log, auditService))
+	})
+}
@@ -0,0 +1,110 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// CreateAPIKeyHandler эндпоинт выпуска API-ключа. Ключ показывается один раз
+func CreateAPIKeyHandler(log *slog.Logger, service *usecases.APIKeyService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.api_keys.CreateAPIKeyHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestCreateAPIKey
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		key, rawKey, err := service.Create(r.Context(), userIDFromClaims(r), usecases.CreateAPIKeyDTO{
+			Name:      req.Name,
+			Scopes:    req.Scopes,
+			ExpiresAt: req.ExpiresAt,
+		})
+		if err != nil {
+			renderAPIKeyError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{Status: "ok", APIKey: rawKey, Key: key})
+	}
+}
+
+// ListAPIKeysHandler эндпоинт списка API-ключей пользователя без самих ключей
+func ListAPIKeysHandler(log *slog.Logger, service *usecases.APIKeyService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.api_keys.ListAPIKeysHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		keys, err := service.List(r.Context(), userIDFromClaims(r))
+		if err != nil {
+			renderAPIKeyError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", Keys: keys})
+	}
+}
+
+// RevokeAPIKeyHandler эндпоинт отзыва API-ключа
+func RevokeAPIKeyHandler(log *slog.Logger, service *usecases.APIKeyService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.api_keys.RevokeAPIKeyHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		keyID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || keyID <= 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "некорректный идентификатор ключа"})
+			return
+		}
+
+		if err := service.Revoke(r.Context(), userIDFromClaims(r), keyID); err != nil {
+			renderAPIKeyError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
+
+func renderAPIKeyError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, repo.ErrAPIKeyNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, Response{Status: "error", Error: "API-ключ не найден"})
+	case errors.Is(err, usecases.ErrInvalidAPIKeyScope):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Недопустимая область действия ключа"})
+	case errors.Is(err, usecases.ErrAPIKeyNameRequired):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Укажите имя ключа"})
+	case errors.Is(err, usecases.ErrAPIKeyExpiresInPast):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Срок действия ключа должен быть в будущем"})
+	default:
+		log.Error("Ошибка работы с API-ключом", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
 	"task-manager/internal/auth/repo"
@@ -14,7 +13,7 @@ import (
 	"task-manager/pkg/logger/sl"
 )
 
-func DeleteHandler(log *slog.Logger, service *usecases.UserService, tokenAuth *jwtauth.JWTAuth) http.HandlerFunc {
+func DeleteHandler(log *slog.Logger, service *usecases.UserService) http.HandlerFunc {
 	const op = "internal.handlers.rest.user.delete.DeleteHandler"
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With(
@@ -26,19 +25,7 @@ func DeleteHandler(log *slog.Logger, service *usecases.UserService, tokenAuth *j
 		_, claims, _ := jwtauth.FromContext(r.Context())
 		userID := claims["user_id"].(float64)
 
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("Ошибка декодирования запроса", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{Status: "error", Error: "неверный формат запроса"})
-
-			return
-		}
-
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("Некорректный запрос", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{Status: "error", Error: "некорректные данные"})
-
+		if !decodeRequest(w, r, log, &req) {
 			return
 		}
 
@@ -0,0 +1,99 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"task-manager/internal/auth/policy"
+	"task-manager/pkg/logger/sl"
+)
+
+// validate Общий валидатор запросов, в ошибках поля называются как в JSON
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// decodeRequest Декодирует и валидирует тело запроса, при ошибке сам отвечает 400
+// со списком полей, не прошедших проверку
+func decodeRequest(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		log.Error("Ошибка декодирования запроса", sl.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "неверный формат запроса"})
+		return false
+	}
+
+	if err := validate.Struct(req); err != nil {
+		log.Info("Некорректный запрос", sl.Err(err))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "некорректные данные", Fields: fieldErrors(err)})
+		return false
+	}
+
+	return true
+}
+
+// renderValidationError Отвечает 400 со списком полей, если err - нарушение политики
+func renderValidationError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) bool {
+	var validationErr *policy.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	log.Info("Данные не соответствуют политике", sl.Err(err))
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, Response{Status: "error", Error: "некорректные данные", Fields: validationErr.Fields})
+	return true
+}
+
+// fieldErrors Переводит ошибки validator в формат policy.FieldError
+func fieldErrors(err error) []policy.FieldError {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	fields := make([]policy.FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, policy.FieldError{
+			Field:   fe.Field(),
+			Code:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+	return fields
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "обязательное поле"
+	case "required_without":
+		return "обязательное поле, если не указано " + strings.ToLower(fe.Param())
+	case "email":
+		return "некорректный email"
+	default:
+		return "некорректное значение"
+	}
+}
+
+func userIDFromClaims(r *http.Request) int {
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	userID, _ := claims["user_id"].(float64)
+	return int(userID)
+}
@@ -3,17 +3,26 @@ package transport_http
 import (
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"task-manager/internal/auth/repo"
 	"task-manager/internal/auth/usecases"
 	"task-manager/pkg/logger/sl"
 )
 
-// LoginHandler эндпоинт авторизации существующего пользователя
-func LoginHandler(log *slog.Logger, service *usecases.UserService, tokenAuth *jwtauth.JWTAuth) http.HandlerFunc {
+// LoginHandler эндпоинт авторизации существующего пользователя. При включенном
+// втором факторе вместо пары токенов возвращает mfa_token для /login/mfa
+func LoginHandler(
+	log *slog.Logger,
+	service *usecases.UserService,
+	tokenService *usecases.TokenService,
+	mfaService *usecases.MFAService,
+	throttler *usecases.LoginThrottler,
+) http.HandlerFunc {
 	const op = "internal.handlers.rest.user.create.LoginHandler"
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := log.With(
@@ -22,18 +31,7 @@ func LoginHandler(log *slog.Logger, service *usecases.UserService, tokenAuth *jw
 		)
 
 		var req Request
-		err := render.DecodeJSON(r.Body, &req)
-		if err != nil {
-			log.Error("Ошибка декодирования запроса", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
-			return
-		}
-
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("validation error", slog.Any("err", err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{Status: "error", Error: "Ошибка валидации"})
+		if !decodeRequest(w, r, log, &req) {
 			return
 		}
 
@@ -42,21 +40,81 @@ func LoginHandler(log *slog.Logger, service *usecases.UserService, tokenAuth *jw
 			Password: req.Password,
 		}
 
+		ip := clientIP(r)
+		// попытка учитывается до проверки пароля, чтобы одновременные запросы
+		// не прошли проверку лимита по одному и тому же счетчику
+		attempt, err := throttler.Reserve(r.Context(), req.Login, ip)
+		if err != nil {
+			renderThrottled(w, r, log, err)
+			return
+		}
+
 		user, err := service.AuthenticateUser(r.Context(), userDTO)
 		if err != nil {
-			if errors.Is(err, usecases.ErrIncorrectCredentials) {
-				log.Info("Ошибка аутентификации")
+			if errors.Is(err, usecases.ErrIncorrectCredentials) || errors.Is(err, repo.ErrUserNotFound) {
+				log.Info("Ошибка аутентификации", slog.String("login", req.Login), slog.String("ip", ip))
+				if err := throttler.Failure(r.Context(), attempt); err != nil {
+					log.Error("Ошибка учета неудачного входа", sl.Err(err))
+				}
 				render.Status(r, http.StatusUnauthorized)
 				render.JSON(w, r, Response{Status: "error", Error: "неверный логин или пароль"})
 				return
 			}
+			if err := throttler.Release(r.Context(), attempt); err != nil {
+				log.Error("Ошибка возврата попытки входа", sl.Err(err))
+			}
+			if errors.Is(err, usecases.ErrEmailNotVerified) {
+				log.Info("Вход с неподтвержденным email", slog.String("login", req.Login))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Подтвердите email, чтобы войти"})
+				return
+			}
+			if errors.Is(err, usecases.ErrAccountDisabled) {
+				log.Info("Вход в заблокированный аккаунт", slog.String("login", req.Login))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Аккаунт заблокирован"})
+				return
+			}
+			if errors.Is(err, usecases.ErrPasswordResetRequired) {
+				log.Info("Вход без обязательного сброса пароля", slog.String("login", req.Login))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Требуется сброс пароля: воспользуйтесь ссылкой из письма"})
+				return
+			}
 			log.Error("Ошибка авторизации пользователя", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
 			return
+		}
 
+		if err := throttler.Success(r.Context(), attempt); err != nil {
+			log.Error("Ошибка сброса счетчика входа", sl.Err(err))
 		}
 
-		_, tokenString, err := tokenAuth.Encode(map[string]interface{}{"user_id": user.ID})
+		renderLoginResult(w, r, log, user, tokenService, mfaService)
+	}
 
+}
+
+// renderLoginResult Завершает вход проверенного пользователя: при включенном втором
+// факторе выдает mfa_token для /login/mfa, иначе пару токенов
+func renderLoginResult(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *slog.Logger,
+	user *repo.User,
+	tokenService *usecases.TokenService,
+	mfaService *usecases.MFAService,
+) {
+	mfaEnabled, err := mfaService.IsEnabled(r.Context(), user.ID)
+	if err != nil {
+		log.Error("Ошибка проверки второго фактора", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+		return
+	}
+	if mfaEnabled {
+		mfaToken, ttl, err := tokenService.IssueMFAToken(r.Context(), user)
 		if err != nil {
 			log.Error("Ошибка генерации токена", sl.Err(err))
 			render.Status(r, http.StatusInternalServerError)
@@ -64,9 +122,57 @@ func LoginHandler(log *slog.Logger, service *usecases.UserService, tokenAuth *jw
 			return
 		}
 
-		log.Info("Пользователь успешно авторизован", slog.Any("user", user.Login))
+		log.Info("Первый шаг входа пройден, ожидается код второго фактора", slog.Int("user_id", user.ID))
 		render.Status(r, http.StatusOK)
-		render.JSON(w, r, Response{Status: "ok", Token: tokenString})
+		render.JSON(w, r, Response{Status: "mfa_required", MFAToken: mfaToken, ExpiresIn: int(ttl.Seconds())})
+		return
+	}
+
+	tokens, err := tokenService.IssueTokens(r.Context(), user, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, usecases.ErrAccountDisabled) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, Response{Status: "error", Error: "Аккаунт заблокирован"})
+			return
+		}
+		log.Error("Ошибка генерации токена", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Ошибка генерации токена"})
+		return
+	}
+
+	log.Info("Пользователь успешно авторизован", slog.Any("user", user.Login))
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, tokensResponse(tokens))
+}
+
+// clientIP Адрес клиента без порта. За доверенным прокси RemoteAddr уже
+// подменен middleware.RealIP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientInfo Данные устройства для записи сессии
+func clientInfo(r *http.Request) usecases.ClientInfo {
+	return usecases.ClientInfo{UserAgent: r.UserAgent(), IP: clientIP(r)}
+}
+
+func renderThrottled(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	var throttled *usecases.ThrottledError
+	if !errors.As(err, &throttled) {
+		log.Error("Ошибка проверки попыток входа", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+		return
 	}
 
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	log.Info("Попытка входа отклонена", slog.Int("retry_after", retryAfter), slog.Bool("locked", throttled.Locked))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	render.Status(r, http.StatusTooManyRequests)
+	render.JSON(w, r, Response{Status: "error", Error: "Слишком много попыток входа, повторите позже"})
 }
@@ -0,0 +1,62 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// LogoutHandler эндпоинт выхода: отзывает текущий токен и refresh-токены этого входа
+func LogoutHandler(log *slog.Logger, tokenService *usecases.TokenService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.logout.LogoutHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token, _, _ := jwtauth.FromContext(r.Context())
+		if err := tokenService.Logout(r.Context(), token); err != nil {
+			log.Error("Ошибка выхода", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
+
+// LogoutAllHandler эндпоинт выхода со всех устройств
+func LogoutAllHandler(log *slog.Logger, tokenService *usecases.TokenService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.logout.LogoutAllHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token, _, _ := jwtauth.FromContext(r.Context())
+		userID, err := usecases.UserIDFromToken(token)
+		if err != nil {
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, Response{Status: "error", Error: "Невалидный токен"})
+			return
+		}
+
+		if err := tokenService.RevokeUserTokens(r.Context(), userID); err != nil {
+			log.Error("Ошибка выхода со всех устройств", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
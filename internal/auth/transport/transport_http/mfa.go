@@ -0,0 +1,207 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// LoginMFAHandler второй шаг входа: обмен mfa_token и кода на пару токенов
+func LoginMFAHandler(log *slog.Logger, tokenService *usecases.TokenService, mfaService *usecases.MFAService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.mfa.LoginMFAHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestLoginMFA
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		mfaToken, userID, err := tokenService.ParseMFAToken(r.Context(), req.MFAToken)
+		if err != nil {
+			if errors.Is(err, usecases.ErrInvalidMFAToken) {
+				log.Info("Невалидный токен второго шага", sl.Err(err))
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, Response{Status: "error", Error: "Войдите заново"})
+				return
+			}
+			log.Error("Ошибка проверки токена второго шага", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		user, err := mfaService.Verify(r.Context(), userID, req.Code)
+		if err != nil {
+			if errors.Is(err, usecases.ErrMFATooManyAttempts) {
+				if err := tokenService.RevokeMFAToken(r.Context(), mfaToken); err != nil {
+					log.Error("Ошибка отзыва токена второго шага", sl.Err(err))
+				}
+			}
+			renderMFAError(w, r, log, err)
+			return
+		}
+
+		// токен второго шага одноразовый
+		if err := tokenService.RevokeMFAToken(r.Context(), mfaToken); err != nil {
+			log.Error("Ошибка отзыва токена второго шага", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		tokens, err := tokenService.IssueTokens(r.Context(), user, clientInfo(r))
+		if err != nil {
+			if errors.Is(err, usecases.ErrAccountDisabled) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Аккаунт заблокирован"})
+				return
+			}
+			log.Error("Ошибка генерации токена", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Ошибка генерации токена"})
+			return
+		}
+
+		log.Info("Пользователь успешно авторизован", slog.String("user", user.Login))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, tokensResponse(tokens))
+	}
+}
+
+// MFAEnrollHandler эндпоинт начала настройки второго фактора: возвращает секрет
+// и otpauth:// ссылку для QR-кода
+func MFAEnrollHandler(log *slog.Logger, mfaService *usecases.MFAService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.mfa.MFAEnrollHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestMFAEnroll
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		enrollment, err := mfaService.Enroll(r.Context(), userIDFromClaims(r), req.Password)
+		if err != nil {
+			renderMFAError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", Secret: enrollment.Secret, OTPAuthURI: enrollment.URI})
+	}
+}
+
+// MFAConfirmHandler эндпоинт включения второго фактора первым кодом из приложения
+func MFAConfirmHandler(log *slog.Logger, mfaService *usecases.MFAService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.mfa.MFAConfirmHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestMFACode
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		codes, err := mfaService.Confirm(r.Context(), userIDFromClaims(r), req.Code)
+		if err != nil {
+			renderMFAError(w, r, log, err)
+			return
+		}
+
+		log.Info("Второй фактор включен")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", RecoveryCodes: codes})
+	}
+}
+
+// MFARecoveryCodesHandler эндпоинт выпуска новых кодов восстановления
+func MFARecoveryCodesHandler(log *slog.Logger, mfaService *usecases.MFAService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.mfa.MFARecoveryCodesHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestMFACode
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		codes, err := mfaService.RegenerateRecoveryCodes(r.Context(), userIDFromClaims(r), req.Code)
+		if err != nil {
+			renderMFAError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", RecoveryCodes: codes})
+	}
+}
+
+// MFADisableHandler эндпоинт отключения второго фактора
+func MFADisableHandler(log *slog.Logger, mfaService *usecases.MFAService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.mfa.MFADisableHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestMFADisable
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		if err := mfaService.Disable(r.Context(), userIDFromClaims(r), req.Password, req.Code); err != nil {
+			renderMFAError(w, r, log, err)
+			return
+		}
+
+		log.Info("Второй фактор отключен")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
+
+func renderMFAError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, usecases.ErrIncorrectCredentials):
+		log.Info("Неверный пароль")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, Response{Status: "error", Error: "Неверный пароль"})
+	case errors.Is(err, usecases.ErrInvalidMFACode):
+		log.Info("Неверный код второго фактора")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, Response{Status: "error", Error: "Неверный код"})
+	case errors.Is(err, usecases.ErrMFATooManyAttempts):
+		log.Warn("Слишком много неверных кодов")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, Response{Status: "error", Error: "Слишком много неверных кодов, попробуйте позже"})
+	case errors.Is(err, usecases.ErrMFAAlreadyEnabled):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{Status: "error", Error: "Второй фактор уже включен"})
+	case errors.Is(err, usecases.ErrMFANotEnabled), errors.Is(err, repo.ErrMFANotFound):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{Status: "error", Error: "Второй фактор не настроен"})
+	default:
+		log.Error("Ошибка второго фактора", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+	}
+}
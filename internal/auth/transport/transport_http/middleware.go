@@ -0,0 +1,224 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"task-manager/internal/auth/usecases"
+	jwtissuer "task-manager/pkg/jwt"
+	"task-manager/pkg/logger/sl"
+)
+
+// RequestMeta Кладет в контекст адрес клиента, User-Agent и идентификатор запроса
+// для журнала безопасности. Ставится после middleware.RequestID и RealIP
+func RequestMeta() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := usecases.ContextWithRequestMeta(r.Context(), usecases.RequestMeta{
+				IP:        clientIP(r),
+				UserAgent: r.UserAgent(),
+				RequestID: middleware.GetReqID(r.Context()),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Authenticate Цепочка проверки токена для защищенных маршрутов: поиск и проверка
+// подписи по набору ключей, затем сверка со списком отозванных токенов.
+// Запросы с заголовком "Authorization: ApiKey ..." проверяются по API-ключу
+func Authenticate(
+	log *slog.Logger,
+	issuer *jwtissuer.Issuer,
+	tokenService *usecases.TokenService,
+	apiKeyService *usecases.APIKeyService,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		bearer := chi.Chain(
+			issuer.Verifier(),          // Ищет и проверяет токен в запросе
+			jwtauth.Authenticator(nil), // Отклоняет запросы без валидного токена
+			AccessTokenOnly(),          // Отклоняет промежуточный токен входа с MFA
+			RevocationChecker(log, tokenService),
+		).Handler(next)
+		apiKey := APIKeyAuthenticator(log, apiKeyService)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := apiKeyFromHeader(r); ok {
+				apiKey.ServeHTTP(w, r)
+				return
+			}
+			bearer.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyAuthenticator Проверяет API-ключ и кладет в контекст токен с его владельцем.
+// GET-запросам нужна область read, остальным - write
+func APIKeyAuthenticator(log *slog.Logger, apiKeyService *usecases.APIKeyService) func(http.Handler) http.Handler {
+	const op = "internal.handlers.rest.user.middleware.APIKeyAuthenticator"
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(slog.String("op", op))
+			rawKey, _ := apiKeyFromHeader(r)
+
+			principal, err := apiKeyService.Authenticate(r.Context(), rawKey)
+			if err != nil {
+				switch {
+				case errors.Is(err, usecases.ErrInvalidAPIKey):
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, Response{Status: "error", Error: "Невалидный API-ключ"})
+				case errors.Is(err, usecases.ErrAccountDisabled):
+					render.Status(r, http.StatusForbidden)
+					render.JSON(w, r, Response{Status: "error", Error: "Аккаунт заблокирован"})
+				default:
+					log.Error("Ошибка проверки API-ключа", sl.Err(err))
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+				}
+				return
+			}
+
+			if !principal.HasScope(scopeForMethod(r.Method)) {
+				log.Info("Недостаточно прав API-ключа", slog.Int("key_id", principal.KeyID), slog.String("method", r.Method))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "У API-ключа нет прав на это действие"})
+				return
+			}
+
+			token, err := principal.Token()
+			if err != nil {
+				log.Error("Ошибка создания токена API-ключа", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(r.Context(), token, nil)))
+		})
+	}
+}
+
+// SessionOnly Отклоняет запросы по API-ключу там, где нужен вход по паролю:
+// управление аккаунтом и самими ключами
+func SessionOnly() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, _ := jwtauth.FromContext(r.Context())
+			if usecases.IsAPIKeyToken(token) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Действие недоступно по API-ключу"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope Требует у API-ключа область scope, запросы по сессии пропускает
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, _ := jwtauth.FromContext(r.Context())
+			if !usecases.TokenHasScope(token, scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "У API-ключа нет прав на это действие"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiKeyFromHeader Достает ключ из заголовка "Authorization: ApiKey K"
+func apiKeyFromHeader(r *http.Request) (string, bool) {
+	scheme, key, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "ApiKey") {
+		return "", false
+	}
+	return strings.TrimSpace(key), true
+}
+
+func scopeForMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return usecases.ScopeRead
+	default:
+		return usecases.ScopeWrite
+	}
+}
+
+// RevocationChecker Отклоняет отозванные токены, ставится после jwtauth.Authenticator
+func RevocationChecker(log *slog.Logger, tokenService *usecases.TokenService) func(http.Handler) http.Handler {
+	const op = "internal.handlers.rest.user.middleware.RevocationChecker"
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, _ := jwtauth.FromContext(r.Context())
+
+			revoked, err := tokenService.IsRevoked(r.Context(), token)
+			if err != nil {
+				log.Error("Ошибка проверки отзыва токена", slog.String("op", op), sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+				return
+			}
+			if revoked {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, Response{Status: "error", Error: "Токен отозван"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole Пропускает только пользователей с одной из ролей, ставится после Authenticate.
+// Роль берется из claim role, токены без него считаются пользовательскими
+func RequireRole(log *slog.Logger, roles ...string) func(http.Handler) http.Handler {
+	const op = "internal.handlers.rest.user.middleware.RequireRole"
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, _ := jwtauth.FromContext(r.Context())
+			role := usecases.RoleFromToken(token)
+
+			if !slices.Contains(roles, role) {
+				log.Info("Недостаточно прав",
+					slog.String("op", op),
+					slog.Int("user_id", userIDFromClaims(r)),
+					slog.String("role", role),
+					slog.String("path", r.URL.Path),
+				)
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Недостаточно прав"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AccessTokenOnly Пропускает только access-токены: токен, выданный после пароля
+// при включенном втором факторе, годится лишь для /login/mfa
+func AccessTokenOnly() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, _ := jwtauth.FromContext(r.Context())
+			if !usecases.IsAccessToken(token) {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, Response{Status: "error", Error: "Вход не завершен: введите код второго фактора"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+// oidcStateCookie Привязка входа к браузеру, который его начал. Lax нужен, чтобы
+// cookie пришла при переходе обратно со страницы провайдера
+const (
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/oidc/callback"
+)
+
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, value string, expiresAt time.Time) {
+	cookie := &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     oidcCookiePath,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expiresAt
+		cookie.MaxAge = int(time.Until(expiresAt).Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// OIDCLoginHandler эндпоинт начала входа через внешнего провайдера: перенаправляет
+// на страницу входа провайдера
+func OIDCLoginHandler(log *slog.Logger, service *usecases.OIDCService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.oidc.OIDCLoginHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		login, err := service.StartLogin(r.Context())
+		if err != nil {
+			log.Error("Ошибка начала входа через провайдера", sl.Err(err))
+			render.Status(r, http.StatusBadGateway)
+			render.JSON(w, r, Response{Status: "error", Error: "Провайдер входа недоступен"})
+			return
+		}
+
+		setOIDCStateCookie(w, r, login.StateBinding, login.ExpiresAt)
+		http.Redirect(w, r, login.AuthURL, http.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler эндпоинт возврата от провайдера. Отвечает так же, как /login
+func OIDCCallbackHandler(
+	log *slog.Logger,
+	service *usecases.OIDCService,
+	tokenService *usecases.TokenService,
+	mfaService *usecases.MFAService,
+) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.oidc.OIDCCallbackHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		// привязка одноразовая, дальше она не нужна при любом исходе
+		var stateBinding string
+		if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+			stateBinding = cookie.Value
+		}
+		setOIDCStateCookie(w, r, "", time.Time{})
+
+		query := r.URL.Query()
+		if providerErr := query.Get("error"); providerErr != "" {
+			log.Info("Провайдер отказал во входе", slog.String("error", providerErr), slog.String("description", query.Get("error_description")))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, Response{Status: "error", Error: "Вход через провайдера отменен"})
+			return
+		}
+		if query.Get("state") == "" || query.Get("code") == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "нет параметров state и code"})
+			return
+		}
+
+		user, err := service.CompleteLogin(r.Context(), query.Get("state"), query.Get("code"), stateBinding)
+		if err != nil {
+			switch {
+			case errors.Is(err, usecases.ErrOIDCStateMismatch):
+				log.Warn("Ответ провайдера пришел не в браузер, начавший вход")
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Вход начат в другом браузере, начните заново"})
+			case errors.Is(err, usecases.ErrInvalidOIDCState):
+				log.Info("Неизвестный state входа через провайдера")
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Вход устарел, начните заново"})
+			case errors.Is(err, usecases.ErrOIDCLoginFailed):
+				log.Warn("Провайдер не подтвердил вход", sl.Err(err))
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, Response{Status: "error", Error: "Не удалось войти через провайдера"})
+			case errors.Is(err, usecases.ErrOIDCAccountNotFound):
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Аккаунт для этой учетной записи не создан"})
+			case errors.Is(err, usecases.ErrAccountDisabled):
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Аккаунт заблокирован"})
+			default:
+				log.Error("Ошибка входа через провайдера", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			}
+			return
+		}
+
+		renderLoginResult(w, r, log, user, tokenService, mfaService)
+	}
+}
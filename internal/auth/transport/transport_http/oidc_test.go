@@ -0,0 +1,185 @@
+package transport_http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/oidc"
+	"testing"
+	"time"
+)
+
+var errProviderDown = errors.New("провайдер недоступен")
+
+// stubProvider Провайдер, у которого обмен кода всегда заканчивается ошибкой:
+// тесту важно только, дошел ли вход до обмена
+type stubProvider struct{}
+
+func (stubProvider) AuthCodeURL(_ context.Context, state, _, _ string) (string, error) {
+	return "https://provider.example/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (stubProvider) Exchange(context.Context, string, string) (string, error) {
+	return "", errProviderDown
+}
+
+func (stubProvider) VerifyIDToken(context.Context, string, string) (*oidc.Claims, error) {
+	return nil, errProviderDown
+}
+
+type stubIdentities struct {
+	usecases.IdentityRepository
+
+	states   map[string]*repo.OIDCState
+	consumed int
+}
+
+func (s *stubIdentities) CreateOIDCState(_ context.Context, state *repo.OIDCState) error {
+	s.states[state.StateHash] = state
+	return nil
+}
+
+func (s *stubIdentities) ConsumeOIDCState(_ context.Context, stateHash string) (*repo.OIDCState, error) {
+	s.consumed++
+	state, ok := s.states[stateHash]
+	if !ok {
+		return nil, repo.ErrOIDCStateNotFound
+	}
+	delete(s.states, stateHash)
+	return state, nil
+}
+
+func newOIDCTestRoutes(t *testing.T) (login, callback http.HandlerFunc, identities *stubIdentities) {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	identities = &stubIdentities{states: make(map[string]*repo.OIDCState)}
+	service := usecases.NewOIDCService(log, stubProvider{}, identities, nil, nil, nil, nil, "test", false, 10*time.Minute)
+
+	return OIDCLoginHandler(log, service), OIDCCallbackHandler(log, service, nil, nil), identities
+}
+
+// startLogin Начинает вход и возвращает state из адреса провайдера и cookie привязки
+func startLogin(t *testing.T, login http.HandlerFunc) (string, *http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	login(rec, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("статус %d, ожидался 302", rec.Code)
+	}
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Location: %v", err)
+	}
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			return location.Query().Get("state"), cookie
+		}
+	}
+	t.Fatal("нет cookie привязки входа")
+	return "", nil
+}
+
+func TestOIDCLoginSetsStateCookie(t *testing.T) {
+	login, _, _ := newOIDCTestRoutes(t)
+
+	state, cookie := startLogin(t, login)
+	if state == "" {
+		t.Fatal("нет state в адресе провайдера")
+	}
+
+	if cookie.Value != hashState(state) {
+		t.Errorf("в cookie не хеш state: %q", cookie.Value)
+	}
+	if cookie.Value == state {
+		t.Error("в cookie сам state")
+	}
+	if !cookie.HttpOnly {
+		t.Error("cookie без HttpOnly")
+	}
+	if cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("SameSite = %v, ожидался Lax", cookie.SameSite)
+	}
+	if cookie.Path != oidcCookiePath {
+		t.Errorf("Path = %q", cookie.Path)
+	}
+	if cookie.MaxAge <= 0 {
+		t.Errorf("MaxAge = %d", cookie.MaxAge)
+	}
+}
+
+func TestOIDCCallbackChecksStateCookie(t *testing.T) {
+	login, callback, identities := newOIDCTestRoutes(t)
+
+	victimState, victimCookie := startLogin(t, login)
+	attackerState, _ := startLogin(t, login)
+
+	tests := []struct {
+		name         string
+		state        string
+		cookie       *http.Cookie
+		wantStatus   int
+		wantConsumed bool
+	}{
+		{name: "нет cookie", state: victimState, wantStatus: http.StatusBadRequest},
+		{name: "ответ на чужой вход", state: attackerState, cookie: victimCookie, wantStatus: http.StatusBadRequest},
+		{
+			name:   "поддельная cookie",
+			state:  victimState,
+			cookie: &http.Cookie{Name: oidcStateCookie, Value: victimState},
+			// cookie с самим state вместо хеша не подходит
+			wantStatus: http.StatusBadRequest,
+		},
+		{name: "свой вход", state: victimState, cookie: victimCookie, wantStatus: http.StatusUnauthorized, wantConsumed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := identities.consumed
+
+			query := url.Values{"state": {tt.state}, "code": {"code-1"}}
+			req := httptest.NewRequest(http.MethodGet, "/oidc/callback?"+query.Encode(), nil)
+			if tt.cookie != nil {
+				req.AddCookie(&http.Cookie{Name: tt.cookie.Name, Value: tt.cookie.Value})
+			}
+			rec := httptest.NewRecorder()
+			callback(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("статус %d, ожидался %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if consumed := identities.consumed > before; consumed != tt.wantConsumed {
+				t.Errorf("state использован: %v, ожидалось %v", consumed, tt.wantConsumed)
+			}
+
+			cleared := false
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == oidcStateCookie && cookie.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if !cleared {
+				t.Error("cookie привязки не удалена")
+			}
+		})
+	}
+
+	// ответ с чужой cookie не израсходовал state, переданный в нем
+	if _, ok := identities.states[hashState(attackerState)]; !ok {
+		t.Error("state атакующего израсходован ответом с чужой cookie")
+	}
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
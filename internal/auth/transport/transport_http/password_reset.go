@@ -0,0 +1,147 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+	"task-manager/pkg/password"
+)
+
+// PasswordResetHandler эндпоинт запроса письма для сброса пароля.
+// Всегда отвечает 202, чтобы по ответу нельзя было узнать, зарегистрирован ли email
+func PasswordResetHandler(log *slog.Logger, service *usecases.RecoveryService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.password_reset.PasswordResetHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestPasswordReset
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		if err := service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+			log.Error("Ошибка запроса сброса пароля", sl.Err(err))
+		}
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
+
+// ConfirmPasswordResetHandler эндпоинт установки нового пароля по токену из письма
+func ConfirmPasswordResetHandler(log *slog.Logger, service *usecases.RecoveryService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.password_reset.ConfirmPasswordResetHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestConfirmPasswordReset
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		err := service.ConfirmPasswordReset(r.Context(), usecases.ConfirmPasswordResetDTO{
+			Token:       req.Token,
+			NewPassword: req.NewPassword,
+		})
+		if err != nil {
+			if renderValidationError(w, r, log, err) {
+				return
+			}
+			if errors.Is(err, usecases.ErrInvalidResetToken) {
+				log.Info("Недействительный токен сброса пароля")
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Ссылка недействительна или устарела"})
+				return
+			}
+			if errors.Is(err, password.ErrPasswordTooLong) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Пароль слишком длинный"})
+				return
+			}
+
+			log.Error("Ошибка сброса пароля", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		log.Info("Пароль успешно сброшен")
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
+
+// resetFormTemplate Страница, на которую ведет ссылка из письма. Форма отправляет
+// токен и новый пароль в POST /password/reset/confirm
+var resetFormTemplate = template.Must(template.New("reset").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Сброс пароля</title>
+</head>
+<body>
+<h1>Сброс пароля</h1>
+<form id="reset">
+<input type="hidden" name="token" value="{{.Token}}">
+<label>Новый пароль <input type="password" name="new_password" autocomplete="new-password" required></label>
+<button type="submit">Сохранить</button>
+</form>
+<p id="result"></p>
+<script>
+document.getElementById("reset").addEventListener("submit", async function (e) {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  const response = await fetch("{{.Action}}", {
+    method: "POST",
+    headers: {"Content-Type": "application/json"},
+    body: JSON.stringify({token: form.get("token"), new_password: form.get("new_password")}),
+  });
+  const body = await response.json().catch(() => ({}));
+  document.getElementById("result").textContent = response.ok ? "Пароль изменен, войдите с новым паролем" : (body.error || "Что-то пошло не так");
+});
+</script>
+</body>
+</html>
+`))
+
+// PasswordResetFormHandler страница установки нового пароля по ссылке из письма
+func PasswordResetFormHandler(log *slog.Logger) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.password_reset.PasswordResetFormHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Ссылка недействительна или устарела"})
+			return
+		}
+
+		// токен в адресе страницы не должен уходить в Referer и оседать в кеше
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		err := resetFormTemplate.Execute(w, struct{ Token, Action string }{
+			Token:  token,
+			Action: r.URL.Path,
+		})
+		if err != nil {
+			log.Error("Ошибка вывода формы сброса пароля", sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// RefreshHandler эндпоинт обмена refresh-токена на новую пару токенов
+func RefreshHandler(log *slog.Logger, tokenService *usecases.TokenService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.refresh.RefreshHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestRefresh
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		tokens, err := tokenService.Refresh(r.Context(), req.RefreshToken)
+		if err != nil {
+			switch {
+			case errors.Is(err, usecases.ErrInvalidRefreshToken), errors.Is(err, usecases.ErrRefreshTokenReused):
+				log.Info("Отказ в обновлении токена", sl.Err(err))
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, Response{Status: "error", Error: "Невалидный refresh-токен"})
+			case errors.Is(err, usecases.ErrAccountDisabled):
+				log.Info("Отказ в обновлении токена заблокированного аккаунта")
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, Response{Status: "error", Error: "Аккаунт заблокирован"})
+			default:
+				log.Error("Ошибка обновления токена", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, Response{Status: "error", Error: "Ошибка генерации токена"})
+			}
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, tokensResponse(tokens))
+	}
+}
+
+func tokensResponse(tokens *usecases.TokenPair) Response {
+	return Response{
+		Status:       "ok",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	}
+}
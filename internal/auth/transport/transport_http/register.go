@@ -4,52 +4,62 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
 	"task-manager/internal/auth/repo"
 	"task-manager/internal/auth/usecases"
 	"task-manager/pkg/logger/sl"
+	"task-manager/pkg/password"
 )
 
 // RegisterHandler эндпоинт регистрации нового пользователя
-func RegisterHandler(log *slog.Logger, service *usecases.UserService) http.HandlerFunc {
+// Если указан email, на него отправляется ссылка подтверждения
+func RegisterHandler(log *slog.Logger, service *usecases.UserService, verification *usecases.VerificationService) http.HandlerFunc {
 	const op = "internal.handlers.rest.user.create.RegisterHandler"
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		var req Request
-		err := render.DecodeJSON(r.Body, &req)
-		if err != nil {
-			log.Error("Ошибка декодирования запроса", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{Status: "error", Error: "Неверный формат запроса"})
-			return
-		}
-
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("invalid request", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{Status: "error", Error: "Некорректные данные"})
+		var req RequestRegister
+		if !decodeRequest(w, r, log, &req) {
 			return
 		}
 
 		userDTO := usecases.UsersDTO{
 			Login:    req.Login,
 			Password: req.Password,
+			Email:    req.Email,
 		}
 
 		user, err := service.RegisterUser(r.Context(), userDTO)
 		if err != nil {
+			if renderValidationError(w, r, log, err) {
+				return
+			}
 			if errors.Is(err, repo.ErrUserExists) {
 				log.Info("Пользователь уже существует", slog.String("login", req.Login))
 				render.Status(r, http.StatusConflict)
 				render.JSON(w, r, Response{Status: "error", Error: "Пользователь с таким логином уже существует"})
 				return
 			}
+			if errors.Is(err, repo.ErrEmailExists) {
+				log.Info("Email уже занят")
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, Response{Status: "error", Error: "Пользователь с таким email уже существует"})
+				return
+			}
+			if errors.Is(err, password.ErrPasswordTooLong) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Пароль слишком длинный"})
+				return
+			}
+			if errors.Is(err, usecases.ErrEmailRequired) {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Для регистрации нужен email"})
+				return
+			}
 
 			log.Error("Ошибка при создании пользователя", sl.Err(err))
 			render.Status(r, http.StatusInternalServerError)
@@ -58,6 +68,11 @@ func RegisterHandler(log *slog.Logger, service *usecases.UserService) http.Handl
 		}
 		log = log.With(slog.String("login", userDTO.Login))
 
+		if err := verification.SendVerification(r.Context(), user); err != nil {
+			// аккаунт уже создан, письмо можно запросить повторно
+			log.Error("Ошибка отправки письма подтверждения", sl.Err(err))
+		}
+
 		log.Info("Пользователь успешно создан")
 		render.Status(r, http.StatusCreated)
 		render.JSON(w, r, Response{Status: "ok", UserID: user.ID})
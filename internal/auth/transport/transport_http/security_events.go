@@ -0,0 +1,142 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+// SecurityEventsHandler эндпоинт журнала безопасности текущего пользователя.
+// Фильтр по типу ?type=login_failed,password_changed, страница ?limit=&offset=
+func SecurityEventsHandler(log *slog.Logger, service *usecases.AuditService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.security_events.SecurityEventsHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, errLimit := queryInt(r, "limit")
+		offset, errOffset := queryInt(r, "offset")
+		if errLimit != nil || errOffset != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "limit и offset должны быть числами"})
+			return
+		}
+
+		page, err := service.ListUserEvents(r.Context(), userIDFromClaims(r), queryList(r, "type"), limit, offset)
+		if err != nil {
+			renderSecurityEventsError(w, r, log, err)
+			return
+		}
+
+		renderSecurityEvents(w, r, page)
+	}
+}
+
+// AdminSecurityEventsHandler эндпоинт журнала безопасности по всем пользователям.
+// Фильтры ?user_id=&type=&login=&ip=&since=&until=, время в RFC 3339
+func AdminSecurityEventsHandler(log *slog.Logger, service *usecases.AuditService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.security_events.AdminSecurityEventsHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+		filter := repo.AuthEventFilter{
+			Types: queryList(r, "type"),
+			Login: query.Get("login"),
+			IP:    query.Get("ip"),
+		}
+
+		var errs []error
+		if query.Get("user_id") != "" {
+			userID, err := queryInt(r, "user_id")
+			errs = append(errs, err)
+			filter.UserID = &userID
+		}
+		var err error
+		filter.Limit, err = queryInt(r, "limit")
+		errs = append(errs, err)
+		filter.Offset, err = queryInt(r, "offset")
+		errs = append(errs, err)
+		filter.Since, err = queryTime(r, "since")
+		errs = append(errs, err)
+		filter.Until, err = queryTime(r, "until")
+		errs = append(errs, err)
+		if err := errors.Join(errs...); err != nil {
+			log.Info("Некорректный фильтр журнала", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "user_id, limit и offset должны быть числами, since и until - временем в RFC 3339"})
+			return
+		}
+
+		page, err := service.ListEvents(r.Context(), filter)
+		if err != nil {
+			renderSecurityEventsError(w, r, log, err)
+			return
+		}
+
+		renderSecurityEvents(w, r, page)
+	}
+}
+
+func renderSecurityEvents(w http.ResponseWriter, r *http.Request, page *usecases.AuthEventsPage) {
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, AuthEventsPageResponse{
+		Status: "ok",
+		Events: page.Events,
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+}
+
+func renderSecurityEventsError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	if errors.Is(err, usecases.ErrInvalidAuthEventType) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Неизвестный тип события"})
+		return
+	}
+
+	log.Error("Ошибка чтения журнала безопасности", sl.Err(err))
+	render.Status(r, http.StatusInternalServerError)
+	render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+}
+
+// queryList Значения параметра, переданного несколько раз или через запятую
+func queryList(r *http.Request, name string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// queryTime Необязательный параметр времени в RFC 3339. Приводится к UTC, чтобы
+// смещение из запроса не терялось при сравнении в базе
+func queryTime(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
@@ -0,0 +1,43 @@
+package transport_http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestQueryTimeUTC(t *testing.T) {
+	want := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "UTC", value: "2026-10-18T09:30:00Z"},
+		{name: "восточное смещение", value: "2026-10-18T12:30:00+03:00"},
+		{name: "западное смещение", value: "2026-10-18T04:30:00-05:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/security-events?since="+url.QueryEscape(tt.value), nil)
+
+			got, err := queryTime(r, "since")
+			if err != nil {
+				t.Fatalf("queryTime: %v", err)
+			}
+			if got.Location() != time.UTC || !got.Equal(want) || got.Hour() != want.Hour() {
+				t.Errorf("queryTime = %s, ожидалось %s", got, want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/security-events?since=yesterday", nil)
+	if _, err := queryTime(r, "since"); err == nil {
+		t.Error("queryTime принял время не в RFC 3339")
+	}
+	if got, err := queryTime(r, "until"); got != nil || err != nil {
+		t.Errorf("queryTime без параметра = (%v, %v)", got, err)
+	}
+}
@@ -0,0 +1,85 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// ListSessionsHandler эндпоинт списка устройств, на которых выполнен вход
+func ListSessionsHandler(log *slog.Logger, tokenService *usecases.TokenService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.sessions.ListSessionsHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		sessions, err := tokenService.ListSessions(r.Context(), userIDFromClaims(r))
+		if err != nil {
+			log.Error("Ошибка получения сессий", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		token, _, _ := jwtauth.FromContext(r.Context())
+		currentID := usecases.SessionIDFromToken(token)
+
+		views := make([]SessionView, 0, len(sessions))
+		for _, s := range sessions {
+			views = append(views, SessionView{
+				ID:         s.ID,
+				UserAgent:  s.UserAgent,
+				IP:         s.IP,
+				CreatedAt:  s.CreatedAt,
+				LastSeenAt: s.LastSeenAt,
+				ExpiresAt:  s.ExpiresAt,
+				Current:    s.ID == currentID,
+			})
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", Sessions: views})
+	}
+}
+
+// RevokeSessionHandler эндпоинт завершения сессии на другом устройстве или текущей
+func RevokeSessionHandler(log *slog.Logger, tokenService *usecases.TokenService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.sessions.RevokeSessionHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		sessionID := chi.URLParam(r, "id")
+		if sessionID == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "некорректный идентификатор сессии"})
+			return
+		}
+
+		if err := tokenService.RevokeSession(r.Context(), userIDFromClaims(r), sessionID); err != nil {
+			if errors.Is(err, repo.ErrSessionNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, Response{Status: "error", Error: "Сессия не найдена"})
+				return
+			}
+			log.Error("Ошибка завершения сессии", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
@@ -1,17 +1,157 @@
 package transport_http
 
+import (
+	"task-manager/internal/auth/policy"
+	"task-manager/internal/auth/repo"
+	"time"
+)
+
 type Request struct {
 	Login    string `json:"login" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
 
+type RequestRegister struct {
+	Login    string `json:"login" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"omitempty,email"`
+}
+
 type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
-	UserID int    `json:"user_id,omitempty"`
-	Token  string `json:"token,omitempty"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	UserID       int    `json:"user_id,omitempty"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	// MFAToken выдается вместо пары токенов, если включен второй фактор
+	MFAToken      string   `json:"mfa_token,omitempty"`
+	Secret        string   `json:"secret,omitempty"`
+	OTPAuthURI    string   `json:"otpauth_uri,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// Fields нарушения по отдельным полям запроса
+	Fields []policy.FieldError `json:"fields,omitempty"`
+	User   *UserView           `json:"user,omitempty"`
+	// APIKey открытый ключ, показывается только при создании
+	APIKey string        `json:"api_key,omitempty"`
+	Key    *repo.APIKey  `json:"key,omitempty"`
+	Keys   []repo.APIKey `json:"keys,omitempty"`
+	// Sessions действующие входы пользователя
+	Sessions []SessionView `json:"sessions,omitempty"`
+}
+
+// SessionView Сессия в ответе пользователю, Current отмечает сессию текущего токена
+type SessionView struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// UserView Пользователь в ответах администратору, без хеша пароля
+type UserView struct {
+	ID                    int        `json:"id"`
+	Login                 string     `json:"login"`
+	Email                 *string    `json:"email,omitempty"`
+	Role                  string     `json:"role"`
+	VerifiedAt            *time.Time `json:"verified_at,omitempty"`
+	DisabledAt            *time.Time `json:"disabled_at,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+func newUserView(u *repo.User) *UserView {
+	return &UserView{
+		ID:                    u.ID,
+		Login:                 u.Login,
+		Email:                 u.Email,
+		Role:                  u.Role,
+		VerifiedAt:            u.VerifiedAt,
+		DisabledAt:            u.DisabledAt,
+		PasswordResetRequired: u.PasswordResetRequired,
+		CreatedAt:             u.CreatedAt,
+		UpdatedAt:             u.UpdatedAt,
+	}
+}
+
+// UsersPageResponse Страница списка пользователей
+type UsersPageResponse struct {
+	Status string     `json:"status"`
+	Users  []UserView `json:"users"`
+	Total  int        `json:"total"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+}
+
+// AuthEventsPageResponse Страница журнала безопасности
+type AuthEventsPageResponse struct {
+	Status string           `json:"status"`
+	Events []repo.AuthEvent `json:"events"`
+	Total  int              `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+type RequestCreateAPIKey struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"omitempty,dive,oneof=read write admin"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type RequestSetRole struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
 }
 
 type RequestDelete struct {
 	Password string `json:"password" validate:"required"`
 }
+
+type RequestRefresh struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type RequestUpdateAccount struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Login           string `json:"login" validate:"required_without=Email"`
+	Email           string `json:"email" validate:"omitempty,email"`
+}
+
+type RequestChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type RequestPasswordReset struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type RequestConfirmPasswordReset struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type RequestResendVerification struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type RequestLoginMFA struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type RequestMFAEnroll struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type RequestMFACode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RequestMFADisable struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
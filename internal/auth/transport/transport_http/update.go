@@ -0,0 +1,129 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/repo"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+	"task-manager/pkg/password"
+)
+
+// UpdateAccountHandler эндпоинт смены логина и email. Все сессии завершаются,
+// в ответе выдается новая пара токенов
+func UpdateAccountHandler(
+	log *slog.Logger,
+	service *usecases.UserService,
+	tokenService *usecases.TokenService,
+	verification *usecases.VerificationService,
+) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.update.UpdateAccountHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestUpdateAccount
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		userID := claims["user_id"].(float64)
+
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		user, err := service.UpdateAccount(r.Context(), userID, usecases.UpdateAccountDTO{
+			CurrentPassword: req.CurrentPassword,
+			Login:           req.Login,
+			Email:           req.Email,
+		})
+		if err != nil {
+			renderAccountError(w, r, log, err)
+			return
+		}
+
+		// после смены адреса email снова не подтвержден
+		if err := verification.SendVerification(r.Context(), user); err != nil {
+			log.Error("Ошибка отправки письма подтверждения", sl.Err(err))
+		}
+
+		renderNewTokens(w, r, log, tokenService, user)
+	}
+}
+
+// ChangePasswordHandler эндпоинт смены пароля. Все сессии завершаются,
+// в ответе выдается новая пара токенов
+func ChangePasswordHandler(log *slog.Logger, service *usecases.UserService, tokenService *usecases.TokenService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.update.ChangePasswordHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestChangePassword
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		userID := claims["user_id"].(float64)
+
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		user, err := service.ChangePassword(r.Context(), userID, usecases.ChangePasswordDTO{
+			CurrentPassword: req.CurrentPassword,
+			NewPassword:     req.NewPassword,
+		})
+		if err != nil {
+			renderAccountError(w, r, log, err)
+			return
+		}
+
+		renderNewTokens(w, r, log, tokenService, user)
+	}
+}
+
+func renderAccountError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	if renderValidationError(w, r, log, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, usecases.ErrIncorrectCredentials):
+		log.Info("Неверный текущий пароль")
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, Response{Status: "error", Error: "Неверный текущий пароль"})
+	case errors.Is(err, password.ErrPasswordTooLong):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Пароль слишком длинный"})
+	case errors.Is(err, repo.ErrEmailExists):
+		log.Info("Email уже занят")
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{Status: "error", Error: "Пользователь с таким email уже существует"})
+	case errors.Is(err, repo.ErrUserExists):
+		log.Info("Логин уже занят")
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, Response{Status: "error", Error: "Пользователь с таким логином уже существует"})
+	default:
+		log.Error("Ошибка изменения аккаунта", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+	}
+}
+
+func renderNewTokens(w http.ResponseWriter, r *http.Request, log *slog.Logger, tokenService *usecases.TokenService, user *repo.User) {
+	tokens, err := tokenService.IssueTokens(r.Context(), user, clientInfo(r))
+	if err != nil {
+		log.Error("Ошибка генерации токена", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Ошибка генерации токена"})
+		return
+	}
+
+	log.Info("Аккаунт успешно изменен", slog.Int("user_id", user.ID))
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, tokensResponse(tokens))
+}
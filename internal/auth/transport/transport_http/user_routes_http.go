@@ -7,25 +7,68 @@ import (
 	"log/slog"
 	"net/http"
 	"task-manager/internal/auth/usecases"
+	jwtissuer "task-manager/pkg/jwt"
 )
 
-func UsersRoutes(r *chi.Mux, log *slog.Logger, userService *usecases.UserService, tokenAuth *jwtauth.JWTAuth) {
+func UsersRoutes(
+	r *chi.Mux,
+	log *slog.Logger,
+	userService *usecases.UserService,
+	tokenService *usecases.TokenService,
+	recoveryService *usecases.RecoveryService,
+	verificationService *usecases.VerificationService,
+	mfaService *usecases.MFAService,
+	apiKeyService *usecases.APIKeyService,
+	oidcService *usecases.OIDCService,
+	auditService *usecases.AuditService,
+	throttler *usecases.LoginThrottler,
+	issuer *jwtissuer.Issuer,
+	authenticate func(http.Handler) http.Handler,
+) {
 	// Публичные маршруты
 	r.Group(func(r chi.Router) {
-		r.Post("/register", RegisterHandler(log, userService))
-		r.Post("/login", LoginHandler(log, userService, tokenAuth))
+		r.Post("/register", RegisterHandler(log, userService, verificationService))
+		r.Post("/login", LoginHandler(log, userService, tokenService, mfaService, throttler))
+		r.Post("/login/mfa", LoginMFAHandler(log, tokenService, mfaService))
+		r.Post("/token/refresh", RefreshHandler(log, tokenService))
+		r.Post("/password/reset", PasswordResetHandler(log, recoveryService))
+		r.Get("/password/reset/confirm", PasswordResetFormHandler(log))
+		r.Post("/password/reset/confirm", ConfirmPasswordResetHandler(log, recoveryService))
+		r.Get("/email/verify", VerifyEmailHandler(log, verificationService))
+		r.Post("/email/verify/resend", ResendVerificationHandler(log, verificationService))
+		r.Get("/.well-known/jwks.json", issuer.JWKSHandler())
+
+		// вход через внешнего провайдера, если он настроен
+		if oidcService != nil {
+			r.Get("/oidc/login", OIDCLoginHandler(log, oidcService))
+			r.Get("/oidc/callback", OIDCCallbackHandler(log, oidcService, tokenService, mfaService))
+		}
 	})
 
 	// Защищенные маршруты
 	r.Group(func(r chi.Router) {
-		r.Use(jwtauth.Verifier(tokenAuth))      // Ищет токен в запросе
-		r.Use(jwtauth.Authenticator(tokenAuth)) // Проверяет токен
+		r.Use(authenticate)  // Проверяет токен и его отзыв
+		r.Use(SessionOnly()) // Аккаунтом и ключами управляют только после входа по паролю
 
 		r.Get("/profile", func(w http.ResponseWriter, r *http.Request) {
 			_, claims, _ := jwtauth.FromContext(r.Context())
 			userID := claims["user_id"].(float64)
 			render.JSON(w, r, map[string]float64{"user_id": userID})
 		})
-		r.Delete("/user", DeleteHandler(log, userService, tokenAuth))
+		r.Patch("/user", UpdateAccountHandler(log, userService, tokenService, verificationService))
+		r.Post("/user/password", ChangePasswordHandler(log, userService, tokenService))
+		r.Delete("/user", DeleteHandler(log, userService))
+		r.Post("/user/mfa/enroll", MFAEnrollHandler(log, mfaService))
+		r.Post("/user/mfa/confirm", MFAConfirmHandler(log, mfaService))
+		r.Post("/user/mfa/recovery-codes", MFARecoveryCodesHandler(log, mfaService))
+		r.Delete("/user/mfa", MFADisableHandler(log, mfaService))
+		r.Post("/logout", LogoutHandler(log, tokenService))
+		r.Post("/logout/all", LogoutAllHandler(log, tokenService))
+		r.Get("/sessions", ListSessionsHandler(log, tokenService))
+		r.Delete("/sessions/{id}", RevokeSessionHandler(log, tokenService))
+		r.Get("/user/security-events", SecurityEventsHandler(log, auditService))
+		r.Post("/user/api-keys", CreateAPIKeyHandler(log, apiKeyService))
+		r.Get("/user/api-keys", ListAPIKeysHandler(log, apiKeyService))
+		r.Delete("/user/api-keys/{id}", RevokeAPIKeyHandler(log, apiKeyService))
 	})
 }
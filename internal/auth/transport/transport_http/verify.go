@@ -0,0 +1,70 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/auth/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// VerifyEmailHandler эндпоинт подтверждения email, на него ведет ссылка из письма
+func VerifyEmailHandler(log *slog.Logger, service *usecases.VerificationService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.verify.VerifyEmailHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "не передан токен"})
+			return
+		}
+
+		if err := service.ConfirmEmail(r.Context(), token); err != nil {
+			if errors.Is(err, usecases.ErrInvalidVerificationToken) {
+				log.Info("Недействительный токен подтверждения email")
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, Response{Status: "error", Error: "Ссылка недействительна или устарела"})
+				return
+			}
+
+			log.Error("Ошибка подтверждения email", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
+
+// ResendVerificationHandler эндпоинт повторной отправки письма подтверждения.
+// Публичный, так как без подтверждения войти может быть нельзя; всегда отвечает 202
+func ResendVerificationHandler(log *slog.Logger, service *usecases.VerificationService) http.HandlerFunc {
+	const op = "internal.handlers.rest.user.verify.ResendVerificationHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RequestResendVerification
+		if !decodeRequest(w, r, log, &req) {
+			return
+		}
+
+		if err := service.ResendVerification(r.Context(), req.Email); err != nil {
+			log.Error("Ошибка повторной отправки подтверждения", sl.Err(err))
+		}
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
@@ -0,0 +1,367 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"task-manager/internal/auth/policy"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"time"
+)
+
+const (
+	defaultUsersPageLimit = 20
+	maxUsersPageLimit     = 100
+)
+
+var (
+	ErrInvalidRole       = errors.New("неизвестная роль")
+	ErrSelfModification  = errors.New("нельзя заблокировать себя или снять с себя роль администратора")
+	ErrUserHasNoEmail    = errors.New("у пользователя нет email для отправки ссылки сброса пароля")
+	ErrBootstrapPassword = errors.New("для создания администратора нужен пароль")
+	ErrBootstrapMismatch = errors.New("пароль администратора не совпадает с паролем существующего пользователя")
+)
+
+// UsersPage Страница списка пользователей
+type UsersPage struct {
+	Users  []repo.User
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// AdminService Управление пользователями администратором
+type AdminService struct {
+	logger     *slog.Logger
+	users      UserAdminRepository
+	revoker    TokenRevoker
+	resets     PasswordResetSender
+	producer   Producer
+	transactor Transactor
+	hasher     PasswordHasher
+	policy     CredentialPolicy
+	audit      AuditRecorder
+}
+
+func NewAdminService(
+	logger *slog.Logger,
+	users UserAdminRepository,
+	revoker TokenRevoker,
+	resets PasswordResetSender,
+	producer Producer,
+	transactor Transactor,
+	hasher PasswordHasher,
+	policy CredentialPolicy,
+	audit AuditRecorder,
+) *AdminService {
+	return &AdminService{
+		logger:     logger,
+		users:      users,
+		revoker:    revoker,
+		resets:     resets,
+		producer:   producer,
+		transactor: transactor,
+		hasher:     hasher,
+		policy:     policy,
+		audit:      audit,
+	}
+}
+
+// ListUsers Возвращает страницу пользователей. Некорректные limit и offset
+// заменяются значениями по умолчанию
+func (s *AdminService) ListUsers(ctx context.Context, limit, offset int) (*UsersPage, error) {
+	const op = "internal.users.admin.ListUsers"
+
+	if limit <= 0 {
+		limit = defaultUsersPageLimit
+	}
+	if limit > maxUsersPageLimit {
+		limit = maxUsersPageLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, total, err := s.users.FindAll(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &UsersPage{Users: users, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID int) (*repo.User, error) {
+	const op = "internal.users.admin.GetUser"
+
+	user, err := s.users.FindOneByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// DisableUser Блокирует аккаунт и завершает все его сессии
+func (s *AdminService) DisableUser(ctx context.Context, adminID, userID int) (*repo.User, error) {
+	const op = "internal.users.admin.DisableUser"
+
+	if adminID == userID {
+		return nil, fmt.Errorf("%s: %w", op, ErrSelfModification)
+	}
+
+	user, changed, err := s.saveAndNotify(ctx, op, adminID, userID, true, events.TypeUserDisabled, func(user *repo.User) (any, error) {
+		if user.DisabledAt != nil {
+			return nil, nil
+		}
+		now := time.Now()
+		user.DisabledAt = &now
+		return events.UserRef{UserID: user.ID, Login: user.Login}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if changed {
+		s.audit.Record(ctx, AuthEventAccountDisabled, user.ID, user.Login, map[string]any{"admin_id": adminID})
+	}
+	return user, nil
+}
+
+// EnableUser Снимает блокировку аккаунта
+func (s *AdminService) EnableUser(ctx context.Context, adminID, userID int) (*repo.User, error) {
+	const op = "internal.users.admin.EnableUser"
+
+	user, changed, err := s.saveAndNotify(ctx, op, adminID, userID, false, events.TypeUserEnabled, func(user *repo.User) (any, error) {
+		if user.DisabledAt == nil {
+			return nil, nil
+		}
+		user.DisabledAt = nil
+		return events.UserRef{UserID: user.ID, Login: user.Login}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if changed {
+		s.audit.Record(ctx, AuthEventAccountEnabled, user.ID, user.Login, map[string]any{"admin_id": adminID})
+	}
+	return user, nil
+}
+
+// ForcePasswordReset Запрещает вход по старому паролю, завершает сессии
+// и отправляет пользователю ссылку для установки нового пароля
+func (s *AdminService) ForcePasswordReset(ctx context.Context, adminID, userID int) (*repo.User, error) {
+	const op = "internal.users.admin.ForcePasswordReset"
+
+	user, _, err := s.saveAndNotify(ctx, op, adminID, userID, true, events.TypeUserPasswordResetForced, func(user *repo.User) (any, error) {
+		// без email пользователь не сможет сменить пароль и останется без доступа
+		if user.Email == nil {
+			return nil, ErrUserHasNoEmail
+		}
+		user.PasswordResetRequired = true
+		return events.UserRef{UserID: user.ID, Login: user.Login}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.resets.SendPasswordReset(ctx, user); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// SetRole Меняет роль пользователя. Старые токены с прежней ролью отзываются
+func (s *AdminService) SetRole(ctx context.Context, adminID, userID int, role string) (*repo.User, error) {
+	const op = "internal.users.admin.SetRole"
+
+	if role != repo.RoleUser && role != repo.RoleAdmin {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidRole)
+	}
+	if adminID == userID && role != repo.RoleAdmin {
+		return nil, fmt.Errorf("%s: %w", op, ErrSelfModification)
+	}
+
+	user, _, err := s.saveAndNotify(ctx, op, adminID, userID, true, events.TypeUserRoleChanged, func(user *repo.User) (any, error) {
+		if user.Role == role {
+			return nil, nil
+		}
+		user.Role = role
+		return events.UserRoleChanged{UserID: user.ID, Login: user.Login, Role: role}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// Bootstrap Создает первого администратора, если администраторов еще нет.
+// Существующий пользователь с таким логином получает роль, только если его пароль
+// совпадает с заданным. Если пользователя нет, создается новый аккаунт с
+// подтвержденным email
+func (s *AdminService) Bootstrap(ctx context.Context, login, password, email string) error {
+	const op = "internal.users.admin.Bootstrap"
+
+	log := s.logger.With(slog.String("op", op), slog.String("login", login))
+
+	exists, err := s.users.HasAdmin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if exists {
+		log.Debug("Администратор уже есть, создание пропущено")
+		return nil
+	}
+
+	user, err := s.users.FindOne(ctx, login)
+	if err == nil {
+		// логин мог занять кто угодно до настройки BOOTSTRAP_ADMIN_LOGIN, поэтому
+		// роль получает только владелец заданного оператором пароля
+		valid, err := s.hasher.Verify(user.PasswordHash, password)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if password == "" || !valid {
+			return fmt.Errorf("%s: %w", op, ErrBootstrapMismatch)
+		}
+
+		err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			user, err := s.users.FindOneByIDForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			user.Role = repo.RoleAdmin
+			if err := s.users.Update(ctx, user); err != nil {
+				return err
+			}
+
+			return emitEvent(ctx, s.producer, events.TypeUserRoleChanged, events.SystemActor(), user.ID, events.UserRoleChanged{
+				UserID: user.ID,
+				Login:  user.Login,
+				Role:   user.Role,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		log.Info("Существующий пользователь назначен администратором", slog.Int("user_id", user.ID))
+		return nil
+	}
+	if !errors.Is(err, repo.ErrUserNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if password == "" {
+		return fmt.Errorf("%s: %w", op, ErrBootstrapPassword)
+	}
+	if err := policy.Join(
+		s.policy.CheckLogin(login),
+		s.policy.CheckPassword("password", password, login),
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	user = &repo.User{
+		Login:        login,
+		PasswordHash: hashedPassword,
+		Role:         repo.RoleAdmin,
+		VerifiedAt:   &now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if email != "" {
+		email = NormalizeEmail(email)
+		user.Email = &email
+	}
+	// как и при регистрации, аккаунт и событие о нем сохраняются вместе
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.users.Create(ctx, user); err != nil {
+			return err
+		}
+
+		registered := events.UserRegistered{UserID: user.ID, Login: user.Login}
+		if user.Email != nil {
+			registered.Email = *user.Email
+		}
+		if err := emitEvent(ctx, s.producer, events.TypeUserRegistered, events.SystemActor(), user.ID, registered); err != nil {
+			return err
+		}
+		return emitEvent(ctx, s.producer, events.TypeUserRoleChanged, events.SystemActor(), user.ID, events.UserRoleChanged{
+			UserID: user.ID,
+			Login:  user.Login,
+			Role:   user.Role,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.audit.Record(ctx, AuthEventRegistered, user.ID, user.Login, map[string]any{"bootstrap": true, "role": user.Role})
+
+	log.Info("Создан первый администратор", slog.Int("user_id", user.ID))
+	return nil
+}
+
+// saveAndNotify Блокирует аккаунт в транзакции, применяет к нему change и сохраняет
+// изменения вместе с событием о действии администратора. change возвращает
+// payload события или nil, если менять нечего. revoke - завершить все сессии
+// пользователя. Второе значение - были ли изменения
+func (s *AdminService) saveAndNotify(
+	ctx context.Context,
+	op string,
+	adminID int,
+	userID int,
+	revoke bool,
+	eventType string,
+	change func(user *repo.User) (any, error),
+) (*repo.User, bool, error) {
+	var user *repo.User
+	changed := false
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.users.FindOneByIDForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		payload, err := change(user)
+		if err != nil || payload == nil {
+			return err
+		}
+		changed = true
+
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+		if revoke {
+			if err := s.revoker.RevokeUserTokens(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		return emitEvent(ctx, s.producer, eventType, events.AdminActor(adminID), user.ID, payload)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !changed {
+		return user, false, nil
+	}
+
+	s.logger.Info("Действие администратора",
+		slog.String("op", op),
+		slog.Int("admin_id", adminID),
+		slog.String("event_type", eventType),
+		slog.Int("user_id", user.ID),
+	)
+	return user, true, nil
+}
@@ -0,0 +1,255 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"log/slog"
+	"slices"
+	"strings"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+// Области действия API-ключей. Сессии после входа по паролю ограничений не имеют
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+const (
+	apiKeyPrefix = "tm_"
+	// apiKeyDisplayLength сколько символов ключа хранится открыто для списка ключей
+	apiKeyDisplayLength = 11
+	// apiKeyTouchInterval как часто обновляется время последнего использования
+	apiKeyTouchInterval = time.Minute
+	tokenUseAPIKey      = "api_key"
+	scopeClaim          = "scope"
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("невалидный, отозванный или истекший API-ключ")
+	ErrInvalidAPIKeyScope  = errors.New("неизвестная область действия API-ключа")
+	ErrAPIKeyNameRequired  = errors.New("у API-ключа должно быть имя")
+	ErrAPIKeyExpiresInPast = errors.New("срок действия API-ключа уже истек")
+)
+
+// APIKeyPrincipal Владелец предъявленного API-ключа и разрешенные ему действия
+type APIKeyPrincipal struct {
+	KeyID  int
+	UserID int
+	Role   string
+	Scopes []string
+}
+
+func (p *APIKeyPrincipal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Token Представляет ключ в виде jwt.Token, чтобы обработчики читали
+// user_id и role из контекста одинаково для ключей и сессий. Токен не подписывается
+// и наружу не отдается
+func (p *APIKeyPrincipal) Token() (jwt.Token, error) {
+	return jwt.NewBuilder().
+		Claim("user_id", float64(p.UserID)).
+		Claim("role", p.Role).
+		Claim(scopeClaim, p.Scopes).
+		Claim(tokenUseClaim, tokenUseAPIKey).
+		Build()
+}
+
+// IsAPIKeyToken Выдан ли токен в контексте по API-ключу
+func IsAPIKeyToken(token jwt.Token) bool {
+	use, _ := token.PrivateClaims()[tokenUseClaim].(string)
+	return use == tokenUseAPIKey
+}
+
+// TokenHasScope Разрешено ли действие токену. Для сессий всегда true
+func TokenHasScope(token jwt.Token, scope string) bool {
+	if !IsAPIKeyToken(token) {
+		return true
+	}
+
+	switch scopes := token.PrivateClaims()[scopeClaim].(type) {
+	case []string:
+		return slices.Contains(scopes, scope)
+	case []interface{}:
+		for _, s := range scopes {
+			if s == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// APIKeyService Персональные API-ключи для скриптов и CI
+type APIKeyService struct {
+	logger     *slog.Logger
+	repository APIKeyRepository
+	users      RepositoryInterface
+	producer   Producer
+}
+
+func NewAPIKeyService(
+	logger *slog.Logger,
+	repository APIKeyRepository,
+	users RepositoryInterface,
+	producer Producer,
+) *APIKeyService {
+	return &APIKeyService{
+		logger:     logger,
+		repository: repository,
+		users:      users,
+		producer:   producer,
+	}
+}
+
+// Create Выпускает ключ. Открытый ключ возвращается только здесь, в базе остается его хеш.
+// Область admin доступна только администраторам
+func (s *APIKeyService) Create(ctx context.Context, userID int, dto CreateAPIKeyDTO) (*repo.APIKey, string, error) {
+	const op = "internal.users.api_keys.Create"
+
+	log := s.logger.With(slog.String("op", op), slog.Int("user_id", userID))
+
+	name := strings.TrimSpace(dto.Name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrAPIKeyNameRequired)
+	}
+	if dto.ExpiresAt != nil && !dto.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrAPIKeyExpiresInPast)
+	}
+
+	user, err := s.users.FindOneByID(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	scopes, err := normalizeScopes(dto.Scopes, user.Role)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := &repo.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    rawKey[:apiKeyDisplayLength],
+		KeyHash:   hashToken(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: dto.ExpiresAt,
+	}
+	if err := s.repository.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	publishEvent(ctx, log, s.producer, events.TypeUserAPIKeyCreated, events.UserActor(userID), userID, events.UserAPIKeyCreated{
+		UserID: userID,
+		KeyID:  key.ID,
+		Prefix: key.Prefix,
+		Scopes: scopes,
+	})
+
+	log.Info("Выпущен API-ключ", slog.Int("key_id", key.ID), slog.Any("scopes", scopes))
+	return key, rawKey, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID int) ([]repo.APIKey, error) {
+	const op = "internal.users.api_keys.List"
+
+	keys, err := s.repository.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID int) error {
+	const op = "internal.users.api_keys.Revoke"
+
+	if err := s.repository.RevokeAPIKey(ctx, userID, keyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("API-ключ отозван", slog.String("op", op), slog.Int("user_id", userID), slog.Int("key_id", keyID))
+	return nil
+}
+
+// Authenticate Проверяет предъявленный ключ. Роль берется у пользователя на момент
+// запроса, поэтому область admin перестает действовать после снятия роли
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*APIKeyPrincipal, error) {
+	const op = "internal.users.api_keys.Authenticate"
+
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+	}
+
+	key, err := s.repository.FindAPIKeyByHash(ctx, hashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+	}
+
+	user, err := s.users.FindOneByID(ctx, key.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidAPIKey)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user.DisabledAt != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrAccountDisabled)
+	}
+
+	scopes := key.Scopes
+	if user.Role != repo.RoleAdmin {
+		scopes = slices.DeleteFunc(slices.Clone(scopes), func(s string) bool { return s == ScopeAdmin })
+	}
+
+	// время использования вспомогательное, ошибка записи не мешает запросу
+	if err := s.repository.TouchAPIKey(ctx, key.ID, apiKeyTouchInterval); err != nil {
+		s.logger.Error("Ошибка обновления времени использования API-ключа", slog.String("op", op), sl.Err(err))
+	}
+
+	return &APIKeyPrincipal{KeyID: key.ID, UserID: user.ID, Role: user.Role, Scopes: scopes}, nil
+}
+
+// normalizeScopes Проверяет области и убирает повторы. Без областей ключ только читает
+func normalizeScopes(scopes []string, role string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{ScopeRead}, nil
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeRead, ScopeWrite:
+		case ScopeAdmin:
+			if role != repo.RoleAdmin {
+				return nil, ErrInvalidAPIKeyScope
+			}
+		default:
+			return nil, ErrInvalidAPIKeyScope
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+
+	return result, nil
+}
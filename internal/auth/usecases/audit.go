@@ -0,0 +1,152 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/logger/sl"
+)
+
+// Типы записей журнала безопасности
+const (
+	AuthEventLoginSucceeded  = "login_succeeded"
+	AuthEventLoginFailed     = "login_failed"
+	AuthEventLoginLocked     = "login_locked"
+	AuthEventRegistered      = "registered"
+	AuthEventAccountDeleted  = "account_deleted"
+	AuthEventPasswordChanged = "password_changed"
+	AuthEventAccountDisabled = "account_disabled"
+	AuthEventAccountEnabled  = "account_enabled"
+)
+
+// AuthEventTypes Все типы записей журнала, по ним проверяется фильтр
+var AuthEventTypes = []string{
+	AuthEventLoginSucceeded,
+	AuthEventLoginFailed,
+	AuthEventLoginLocked,
+	AuthEventRegistered,
+	AuthEventAccountDeleted,
+	AuthEventPasswordChanged,
+	AuthEventAccountDisabled,
+	AuthEventAccountEnabled,
+}
+
+const (
+	defaultAuthEventsPageLimit = 50
+	maxAuthEventsPageLimit     = 200
+)
+
+var (
+	ErrInvalidAuthEventType = errors.New("неизвестный тип записи журнала безопасности")
+)
+
+// RequestMeta Данные запроса, которые попадают в журнал безопасности
+type RequestMeta struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type requestMetaKey struct{}
+
+// ContextWithRequestMeta Кладет данные запроса в контекст. Транспорт делает это
+// для каждого запроса, поэтому сервисам не нужно передавать их явно
+func ContextWithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext Данные запроса из контекста, пустые вне HTTP-запроса
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+// AuthEventsPage Страница журнала безопасности
+type AuthEventsPage struct {
+	Events []repo.AuthEvent
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// AuditService Журнал входов и изменений аккаунтов
+type AuditService struct {
+	logger     *slog.Logger
+	repository AuthEventRepository
+}
+
+func NewAuditService(logger *slog.Logger, repository AuthEventRepository) *AuditService {
+	return &AuditService{logger: logger, repository: repository}
+}
+
+// Record Записывает событие с данными текущего запроса. userID 0 - пользователь
+// неизвестен. Ошибка записи только логируется: журнал не должен ломать вход
+func (s *AuditService) Record(ctx context.Context, eventType string, userID int, login string, details map[string]any) {
+	const op = "internal.users.audit.Record"
+
+	meta := RequestMetaFromContext(ctx)
+	event := &repo.AuthEvent{
+		Type:      eventType,
+		Login:     login,
+		IP:        meta.IP,
+		UserAgent: truncateUserAgent(meta.UserAgent),
+		RequestID: meta.RequestID,
+		Details:   details,
+	}
+	if userID > 0 {
+		event.UserID = &userID
+	}
+
+	// запись не должна пропасть из-за того, что клиент разорвал соединение
+	if err := s.repository.CreateAuthEvent(context.WithoutCancel(ctx), event); err != nil {
+		s.logger.Error("Ошибка записи в журнал безопасности",
+			slog.String("op", op),
+			slog.String("type", eventType),
+			slog.Int("user_id", userID),
+			sl.Err(err),
+		)
+	}
+}
+
+// ListUserEvents Журнал одного пользователя
+func (s *AuditService) ListUserEvents(ctx context.Context, userID int, types []string, limit, offset int) (*AuthEventsPage, error) {
+	const op = "internal.users.audit.ListUserEvents"
+
+	page, err := s.ListEvents(ctx, repo.AuthEventFilter{UserID: &userID, Types: types, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+// ListEvents Журнал по произвольному фильтру для администратора. Некорректные
+// limit и offset заменяются значениями по умолчанию
+func (s *AuditService) ListEvents(ctx context.Context, filter repo.AuthEventFilter) (*AuthEventsPage, error) {
+	const op = "internal.users.audit.ListEvents"
+
+	for _, eventType := range filter.Types {
+		if !slices.Contains(AuthEventTypes, eventType) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidAuthEventType)
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuthEventsPageLimit
+	}
+	if filter.Limit > maxAuthEventsPageLimit {
+		filter.Limit = maxAuthEventsPageLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	events, total, err := s.repository.ListAuthEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &AuthEventsPage{Events: events, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
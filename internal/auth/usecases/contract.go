@@ -3,16 +3,149 @@ package usecases
 
 import (
 	"context"
+	"task-manager/internal/auth/policy"
 	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/oidc"
+	"time"
 )
 
 type RepositoryInterface interface {
 	Create(ctx context.Context, u *repo.User) error
 	FindOne(ctx context.Context, login string) (*repo.User, error)
 	FindOneByID(ctx context.Context, id int) (*repo.User, error)
+	FindOneByIDForUpdate(ctx context.Context, id int) (*repo.User, error)
+	FindOneByEmail(ctx context.Context, email string) (*repo.User, error)
+	Update(ctx context.Context, u *repo.User) error
+	UpdatePasswordHash(ctx context.Context, id int, oldHash, newHash string) (bool, error)
 	Delete(ctx context.Context, id int) error
 }
 
+// Producer Отправка доменных событий. Реализация в pkg/outbox сохраняет событие
+// в транзакции из ctx, в Kafka его переносит outbox.Relay
 type Producer interface {
-	SendMessage(key, value string) error
+	Publish(ctx context.Context, event *events.Event) error
+}
+
+// Transactor Выполняет fn в одной транзакции: репозитории и Producer, получившие
+// ctx из fn, пишут в нее. Реализация в pkg/clients/posgresql
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, t *repo.RefreshToken) error
+	FindRefreshToken(ctx context.Context, tokenHash string) (*repo.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id int) (bool, error)
+	RevokeRefreshFamily(ctx context.Context, familyID string) error
+}
+
+type RevocationRepository interface {
+	RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	RevokeAllUserTokens(ctx context.Context, userID int, cutoff time.Time) error
+	UserTokenCutoff(ctx context.Context, userID int) (time.Time, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID int) error
+	IsTokenRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// TokenRevoker Отзыв всех токенов пользователя при удалении аккаунта или смене пароля
+type TokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userID int) error
+}
+
+type OneTimeTokenRepository interface {
+	CreateOneTimeToken(ctx context.Context, t *repo.OneTimeToken) error
+	FindOneTimeToken(ctx context.Context, tokenHash string, purpose string) (*repo.OneTimeToken, error)
+	ConsumeOneTimeToken(ctx context.Context, tokenHash string, purpose string) (*repo.OneTimeToken, error)
+}
+
+type MFARepository interface {
+	FindMFA(ctx context.Context, userID int) (*repo.MFA, error)
+	SaveMFASecret(ctx context.Context, userID int, secret string) error
+	EnableMFA(ctx context.Context, userID int, codeHashes []string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	DeleteMFA(ctx context.Context, userID int) error
+	UseMFAStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	RegisterMFAFailure(ctx context.Context, userID int, maxAttempts int, lockout time.Duration) (int, error)
+	ResetMFAFailures(ctx context.Context, userID int) error
+}
+
+// LoginAttemptStore Счетчики неудачных входов: в памяти или в Postgres
+type LoginAttemptStore interface {
+	ReserveLoginAttempt(ctx context.Context, key string, now time.Time, window time.Duration) (repo.LoginAttempts, error)
+	ReleaseLoginAttempt(ctx context.Context, key string) error
+	RegisterLoginFailure(ctx context.Context, key string, now time.Time) error
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+	CleanupLoginAttempts(ctx context.Context, olderThan time.Time) error
+}
+
+// PasswordHasher Хеширование паролей, реализация в pkg/password
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
+}
+
+// CredentialPolicy Требования к логинам и паролям, реализация в internal/auth/policy
+type CredentialPolicy interface {
+	CheckLogin(login string) []policy.FieldError
+	CheckPassword(field, password, login string) []policy.FieldError
+}
+
+// UserAdminRepository Пользователи с выборками для администрирования
+type UserAdminRepository interface {
+	RepositoryInterface
+	FindAll(ctx context.Context, limit, offset int) ([]repo.User, int, error)
+	HasAdmin(ctx context.Context) (bool, error)
+}
+
+// PasswordResetSender Отправка ссылки сброса пароля при принудительном сбросе
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, user *repo.User) error
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, k *repo.APIKey) error
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (*repo.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID int) ([]repo.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id int) error
+	TouchAPIKey(ctx context.Context, id int, interval time.Duration) error
+}
+
+// OIDCProvider Внешний OpenID Connect провайдер, реализация в pkg/oidc
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*oidc.Claims, error)
+}
+
+type IdentityRepository interface {
+	CreateOIDCState(ctx context.Context, s *repo.OIDCState) error
+	ConsumeOIDCState(ctx context.Context, stateHash string) (*repo.OIDCState, error)
+	CleanupOIDCStates(ctx context.Context) error
+	FindIdentity(ctx context.Context, provider, subject string) (*repo.UserIdentity, error)
+	LinkIdentity(ctx context.Context, i *repo.UserIdentity) error
+	CreateUserWithIdentity(ctx context.Context, u *repo.User, i *repo.UserIdentity) error
+	TouchIdentity(ctx context.Context, id int, email *string) error
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, s *repo.Session) error
+	RenewSession(ctx context.Context, id, accessJTI string, expiresAt time.Time) error
+	ListSessions(ctx context.Context, userID int) ([]repo.Session, error)
+	RevokeSession(ctx context.Context, userID int, id string) error
+	RevokeUserSessions(ctx context.Context, userID int) error
+	TouchSession(ctx context.Context, id string, interval time.Duration) (bool, error)
+}
+
+type AuthEventRepository interface {
+	CreateAuthEvent(ctx context.Context, e *repo.AuthEvent) error
+	ListAuthEvents(ctx context.Context, filter repo.AuthEventFilter) ([]repo.AuthEvent, int, error)
+}
+
+// AuditRecorder Запись в журнал безопасности, реализация в AuditService
+type AuditRecorder interface {
+	Record(ctx context.Context, eventType string, userID int, login string, details map[string]any)
 }
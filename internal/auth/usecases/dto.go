@@ -1,6 +1,38 @@
 package usecases
 
+import "time"
+
 type UsersDTO struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type UpdateAccountDTO struct {
+	CurrentPassword string `json:"current_password"`
+	Login           string `json:"login"`
+	Email           string `json:"email"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ConfirmPasswordResetDTO struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type CreateAPIKeyDTO struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt nil - ключ бессрочный
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ClientInfo Устройство, с которого выполнен вход
+type ClientInfo struct {
+	UserAgent string
+	IP        string
 }
@@ -0,0 +1,50 @@
+package usecases
+
+import (
+	"context"
+	"log/slog"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+)
+
+// emitEvent Создает и отправляет доменное событие. Вызывается внутри
+// Transactor.WithinTx вместе с изменением, к которому относится событие:
+// ошибка отменяет и изменение, и событие
+func emitEvent(
+	ctx context.Context,
+	producer Producer,
+	eventType string,
+	actor events.Actor,
+	subjectUserID int,
+	payload any,
+) error {
+	event, err := events.New(eventType, actor, subjectUserID, payload)
+	if err != nil {
+		return err
+	}
+
+	return producer.Publish(ctx, event)
+}
+
+// publishEvent Создает и отправляет доменное событие. subjectUserID - пользователь,
+// к которому относится событие, 0 - событие ни к кому не привязано. Ошибка
+// отправки только логируется и не отменяет уже выполненное действие
+func publishEvent(
+	ctx context.Context,
+	log *slog.Logger,
+	producer Producer,
+	eventType string,
+	actor events.Actor,
+	subjectUserID int,
+	payload any,
+) {
+	event, err := events.New(eventType, actor, subjectUserID, payload)
+	if err != nil {
+		log.Error("Ошибка создания события", slog.String("event_type", eventType), sl.Err(err))
+		return
+	}
+
+	if err := producer.Publish(ctx, event); err != nil {
+		log.Error("Ошибка отправки события", slog.String("event_type", eventType), sl.Err(err))
+	}
+}
@@ -0,0 +1,321 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/totp"
+	"time"
+)
+
+const (
+	recoveryCodesCount = 10
+	// maxMFAAttempts после стольких неверных кодов подряд проверка кодов блокируется.
+	// Счетчик хранится у пользователя, поэтому новый вход по паролю его не сбрасывает
+	maxMFAAttempts = 5
+	// mfaLockout блокировка после maxMFAAttempts и после каждой следующей неудачи
+	mfaLockout = 15 * time.Minute
+)
+
+var (
+	ErrMFAAlreadyEnabled  = errors.New("второй фактор уже включен")
+	ErrMFANotEnabled      = errors.New("второй фактор не включен")
+	ErrInvalidMFACode     = errors.New("неверный код")
+	ErrMFATooManyAttempts = errors.New("слишком много неверных кодов")
+)
+
+// MFAEnrollment Данные для добавления аккаунта в приложение-аутентификатор
+type MFAEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAService Второй фактор по TOTP (RFC 6238) с одноразовыми кодами восстановления
+type MFAService struct {
+	logger     *slog.Logger
+	repository MFARepository
+	users      RepositoryInterface
+	producer   Producer
+	hasher     PasswordHasher
+	issuerName string
+}
+
+func NewMFAService(
+	logger *slog.Logger,
+	repository MFARepository,
+	users RepositoryInterface,
+	producer Producer,
+	hasher PasswordHasher,
+	issuerName string,
+) *MFAService {
+	return &MFAService{
+		logger:     logger,
+		repository: repository,
+		users:      users,
+		producer:   producer,
+		hasher:     hasher,
+		issuerName: issuerName,
+	}
+}
+
+// Enroll Выдает новый секрет. Второй фактор начинает действовать только после Confirm
+func (s *MFAService) Enroll(ctx context.Context, userID int, password string) (*MFAEnrollment, error) {
+	const op = "internal.users.mfa.Enroll"
+
+	user, err := s.checkPassword(ctx, userID, password)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if enabled {
+		return nil, fmt.Errorf("%s: %w", op, ErrMFAAlreadyEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.repository.SaveMFASecret(ctx, userID, secret); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &MFAEnrollment{Secret: secret, URI: totp.URI(s.issuerName, user.Login, secret)}, nil
+}
+
+// Confirm Включает второй фактор по первому коду из приложения и возвращает
+// коды восстановления. Они показываются один раз, в базе хранятся только хеши
+func (s *MFAService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	const op = "internal.users.mfa.Confirm"
+
+	mfa, err := s.repository.FindMFA(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if mfa.EnabledAt != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrMFAAlreadyEnabled)
+	}
+
+	if err := s.checkTOTP(ctx, mfa, code); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.repository.EnableMFA(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.sendEvent(ctx, events.TypeUserMFAEnabled, userID)
+	return codes, nil
+}
+
+// Disable Отключает второй фактор, требуя пароль и действующий код
+func (s *MFAService) Disable(ctx context.Context, userID int, password, code string) error {
+	const op = "internal.users.mfa.Disable"
+
+	if _, err := s.checkPassword(ctx, userID, password); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := s.Verify(ctx, userID, code); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.repository.DeleteMFA(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.sendEvent(ctx, events.TypeUserMFADisabled, userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes Заменяет коды восстановления, старые перестают действовать
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	const op = "internal.users.mfa.RegenerateRecoveryCodes"
+
+	if _, err := s.Verify(ctx, userID, code); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.repository.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return codes, nil
+}
+
+func (s *MFAService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	const op = "internal.users.mfa.IsEnabled"
+
+	mfa, err := s.repository.FindMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return mfa.EnabledAt != nil, nil
+}
+
+// Verify Проверяет код из приложения или код восстановления.
+// После maxMFAAttempts неверных кодов подряд проверка блокируется на mfaLockout,
+// и до ее окончания возвращается ErrMFATooManyAttempts
+func (s *MFAService) Verify(ctx context.Context, userID int, code string) (*repo.User, error) {
+	const op = "internal.users.mfa.Verify"
+
+	mfa, err := s.repository.FindMFA(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnabled)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if mfa.EnabledAt == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrMFANotEnabled)
+	}
+	// во время блокировки код не проверяется, чтобы перебор не продолжался
+	if mfa.LockedUntil != nil && mfa.LockedUntil.After(time.Now()) {
+		return nil, fmt.Errorf("%s: %w", op, ErrMFATooManyAttempts)
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		err = s.checkTOTP(ctx, mfa, code)
+	} else {
+		err = s.checkRecoveryCode(ctx, userID, code)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return nil, fmt.Errorf("%s: %w", op, s.registerFailure(ctx, userID))
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.repository.ResetMFAFailures(ctx, userID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := s.users.FindOneByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *MFAService) checkTOTP(ctx context.Context, mfa *repo.MFA, code string) error {
+	step, ok, err := totp.Validate(mfa.Secret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.repository.UseMFAStep(ctx, mfa.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		// код уже был принят, повторно он не действует
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (s *MFAService) checkRecoveryCode(ctx context.Context, userID int, code string) error {
+	used, err := s.repository.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	s.logger.Info("Использован код восстановления", slog.Int("user_id", userID))
+	return nil
+}
+
+// registerFailure Считает неверный код и решает, можно ли попробовать еще раз.
+// Счетчик сбрасывается только верным кодом
+func (s *MFAService) registerFailure(ctx context.Context, userID int) error {
+	attempts, err := s.repository.RegisterMFAFailure(ctx, userID, maxMFAAttempts, mfaLockout)
+	if err != nil {
+		return err
+	}
+	if attempts < maxMFAAttempts {
+		return ErrInvalidMFACode
+	}
+
+	s.logger.Warn("Превышено число попыток ввода кода, проверка заблокирована",
+		slog.Int("user_id", userID),
+		slog.Int("attempts", attempts),
+		slog.Duration("lockout", mfaLockout),
+	)
+	return ErrMFATooManyAttempts
+}
+
+func (s *MFAService) checkPassword(ctx context.Context, userID int, password string) (*repo.User, error) {
+	user, err := s.users.FindOneByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrIncorrectCredentials
+	}
+	return user, nil
+}
+
+func (s *MFAService) sendEvent(ctx context.Context, eventType string, userID int) {
+	publishEvent(ctx, s.logger, s.producer, eventType, events.UserActor(userID), userID, events.UserRef{UserID: userID})
+}
+
+// generateRecoveryCodes Возвращает коды вида xxxxx-xxxxx и их хеши
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode Код принимается без учета регистра, дефисов и пробелов
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
@@ -0,0 +1,309 @@
+package usecases
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+	"task-manager/pkg/oidc"
+	"time"
+	"unicode"
+)
+
+// provisionLoginAttempts сколько вариантов логина перебирается при создании аккаунта
+const provisionLoginAttempts = 5
+
+var (
+	ErrInvalidOIDCState    = errors.New("вход через провайдера не начинался или устарел")
+	ErrOIDCStateMismatch   = errors.New("вход через провайдера начат в другом браузере")
+	ErrOIDCLoginFailed     = errors.New("провайдер не подтвердил вход")
+	ErrOIDCAccountNotFound = errors.New("нет аккаунта, привязанного к учетной записи провайдера")
+)
+
+// OIDCService Вход через внешнего OpenID Connect провайдера по authorization code с PKCE.
+// Учетная запись провайдера привязывается к пользователю по sub, при первом входе -
+// к аккаунту с тем же подтвержденным email или к новому аккаунту
+type OIDCService struct {
+	logger        *slog.Logger
+	provider      OIDCProvider
+	identities    IdentityRepository
+	users         RepositoryInterface
+	policy        CredentialPolicy
+	producer      Producer
+	audit         AuditRecorder
+	providerName  string
+	autoProvision bool
+	stateTTL      time.Duration
+}
+
+func NewOIDCService(
+	logger *slog.Logger,
+	provider OIDCProvider,
+	identities IdentityRepository,
+	users RepositoryInterface,
+	policy CredentialPolicy,
+	producer Producer,
+	audit AuditRecorder,
+	providerName string,
+	autoProvision bool,
+	stateTTL time.Duration,
+) *OIDCService {
+	return &OIDCService{
+		logger:        logger,
+		provider:      provider,
+		identities:    identities,
+		users:         users,
+		policy:        policy,
+		producer:      producer,
+		audit:         audit,
+		providerName:  providerName,
+		autoProvision: autoProvision,
+		stateTTL:      stateTTL,
+	}
+}
+
+// OIDCLogin Начатый вход через провайдера. StateBinding сохраняется в браузере,
+// который начал вход, и предъявляется вместе с ответом провайдера
+type OIDCLogin struct {
+	AuthURL      string
+	StateBinding string
+	ExpiresAt    time.Time
+}
+
+// StartLogin Создает state, nonce и code_verifier и возвращает адрес страницы входа провайдера
+func (s *OIDCService) StartLogin(ctx context.Context) (*OIDCLogin, error) {
+	const op = "internal.users.oidc.StartLogin"
+
+	state, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	codeVerifier, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	login := &OIDCLogin{
+		AuthURL:      authURL,
+		StateBinding: hashToken(state),
+		ExpiresAt:    time.Now().Add(s.stateTTL),
+	}
+	err = s.identities.CreateOIDCState(ctx, &repo.OIDCState{
+		StateHash:    login.StateBinding,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    login.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return login, nil
+}
+
+// CompleteLogin Проверяет ответ провайдера и возвращает пользователя, которому
+// принадлежит учетная запись провайдера. stateBinding - значение из StartLogin,
+// сохраненное в браузере: без него чужой ответ провайдера, подсунутый по ссылке,
+// залогинил бы жертву в аккаунт атакующего
+func (s *OIDCService) CompleteLogin(ctx context.Context, state, code, stateBinding string) (*repo.User, error) {
+	const op = "internal.users.oidc.CompleteLogin"
+
+	log := s.logger.With(slog.String("op", op))
+
+	if stateBinding == "" || subtle.ConstantTimeCompare([]byte(stateBinding), []byte(hashToken(state))) != 1 {
+		return nil, fmt.Errorf("%s: %w", op, ErrOIDCStateMismatch)
+	}
+
+	stored, err := s.identities.ConsumeOIDCState(ctx, hashToken(state))
+	if err != nil {
+		if errors.Is(err, repo.ErrOIDCStateNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidOIDCState)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	idToken, err := s.provider.Exchange(ctx, code, stored.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrOIDCLoginFailed, err)
+	}
+	claims, err := s.provider.VerifyIDToken(ctx, idToken, stored.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrOIDCLoginFailed, err)
+	}
+
+	user, err := s.resolveUser(ctx, log, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user.DisabledAt != nil {
+		s.audit.Record(ctx, AuthEventLoginFailed, user.ID, user.Login, map[string]any{"reason": "account_disabled", "method": "oidc"})
+		return nil, fmt.Errorf("%s: %w", op, ErrAccountDisabled)
+	}
+
+	s.audit.Record(ctx, AuthEventLoginSucceeded, user.ID, user.Login, map[string]any{"method": "oidc", "provider": s.providerName})
+
+	publishEvent(ctx, log, s.producer, events.TypeUserLoggedIn, events.UserActor(user.ID), user.ID, events.UserLoggedIn{
+		UserID:   user.ID,
+		Login:    user.Login,
+		Method:   "oidc",
+		Provider: s.providerName,
+	})
+
+	return user, nil
+}
+
+// RunCleanup Периодически удаляет брошенные входы, пока не отменен ctx
+func (s *OIDCService) RunCleanup(ctx context.Context, interval time.Duration) {
+	const op = "internal.users.oidc.RunCleanup"
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.identities.CleanupOIDCStates(ctx); err != nil {
+				s.logger.Error("Ошибка очистки состояний входа через провайдера", slog.String("op", op), sl.Err(err))
+			}
+		}
+	}
+}
+
+func (s *OIDCService) resolveUser(ctx context.Context, log *slog.Logger, claims *oidc.Claims) (*repo.User, error) {
+	email := optionalEmail(claims.Email)
+
+	identity, err := s.identities.FindIdentity(ctx, s.providerName, claims.Subject)
+	if err == nil {
+		if err := s.identities.TouchIdentity(ctx, identity.ID, email); err != nil {
+			log.Error("Ошибка обновления внешней учетной записи", sl.Err(err))
+		}
+		return s.users.FindOneByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, repo.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// привязка по email только если адрес подтвержден и провайдером, и у нас,
+	// иначе чужой аккаунт можно захватить, указав у провайдера его адрес
+	if email != nil && claims.EmailVerified {
+		user, err := s.users.FindOneByEmail(ctx, *email)
+		if err == nil && user.VerifiedAt != nil {
+			identity := &repo.UserIdentity{UserID: user.ID, Provider: s.providerName, Subject: claims.Subject, Email: email}
+			if err := s.identities.LinkIdentity(ctx, identity); err != nil {
+				return nil, err
+			}
+			log.Info("Учетная запись провайдера привязана по email", slog.Int("user_id", user.ID))
+			return user, nil
+		}
+		if err != nil && !errors.Is(err, repo.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	if !s.autoProvision {
+		return nil, ErrOIDCAccountNotFound
+	}
+	return s.provision(ctx, log, claims, email)
+}
+
+// provision Создает аккаунт без локального пароля. Логин берется из preferred_username
+// или email, при занятости к нему добавляется номер
+func (s *OIDCService) provision(ctx context.Context, log *slog.Logger, claims *oidc.Claims, email *string) (*repo.User, error) {
+	base := s.loginCandidate(claims)
+
+	for attempt := 1; attempt <= provisionLoginAttempts+1; attempt++ {
+		login := base
+		switch {
+		case attempt == provisionLoginAttempts+1:
+			suffix, err := randomToken(4)
+			if err != nil {
+				return nil, err
+			}
+			login = fmt.Sprintf("%s-%s", base, strings.ToLower(suffix))
+		case attempt > 1:
+			login = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		now := time.Now()
+		user := &repo.User{Login: login, Email: email, CreatedAt: now, UpdatedAt: now}
+		if email != nil && claims.EmailVerified {
+			user.VerifiedAt = &now
+		}
+		identity := &repo.UserIdentity{Provider: s.providerName, Subject: claims.Subject, Email: email}
+
+		err := s.identities.CreateUserWithIdentity(ctx, user, identity)
+		switch {
+		case err == nil:
+			log.Info("Создан аккаунт при входе через провайдера", slog.Int("user_id", user.ID), slog.String("login", login))
+			return user, nil
+		case errors.Is(err, repo.ErrEmailExists):
+			// адрес занят неподтвержденным аккаунтом, новый создается без email
+			email = nil
+			attempt--
+		case errors.Is(err, repo.ErrIdentityExists):
+			// параллельный вход той же учетной записи уже создал аккаунт
+			existing, err := s.identities.FindIdentity(ctx, s.providerName, claims.Subject)
+			if err != nil {
+				return nil, err
+			}
+			return s.users.FindOneByID(ctx, existing.UserID)
+		case errors.Is(err, repo.ErrUserExists):
+			continue
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, repo.ErrUserExists
+}
+
+// loginCandidate Логин из данных провайдера, приведенный к правилам политики логинов
+func (s *OIDCService) loginCandidate(claims *oidc.Claims) string {
+	candidates := []string{claims.PreferredUsername}
+	if local, _, found := strings.Cut(claims.Email, "@"); found {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		login := sanitizeLogin(candidate)
+		if login != "" && len(s.policy.CheckLogin(login)) == 0 {
+			return login
+		}
+	}
+	return "user"
+}
+
+// sanitizeLogin Оставляет латинские буквы, цифры и символы "._-"
+func sanitizeLogin(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-", r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func optionalEmail(email string) *string {
+	email = NormalizeEmail(email)
+	if email == "" {
+		return nil
+	}
+	return &email
+}
@@ -0,0 +1,211 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"task-manager/internal/auth/policy"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+	"task-manager/pkg/mailer"
+	"time"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("ссылка для сброса пароля недействительна или устарела")
+)
+
+// RecoveryService Восстановление доступа к аккаунту по ссылке из письма
+type RecoveryService struct {
+	logger     *slog.Logger
+	users      RepositoryInterface
+	tokens     OneTimeTokenRepository
+	mailer     mailer.Mailer
+	revoker    TokenRevoker
+	producer   Producer
+	transactor Transactor
+	hasher     PasswordHasher
+	policy     CredentialPolicy
+	audit      AuditRecorder
+	ttl        time.Duration
+	publicURL  string
+}
+
+func NewRecoveryService(
+	logger *slog.Logger,
+	users RepositoryInterface,
+	tokens OneTimeTokenRepository,
+	mailer mailer.Mailer,
+	revoker TokenRevoker,
+	producer Producer,
+	transactor Transactor,
+	hasher PasswordHasher,
+	policy CredentialPolicy,
+	audit AuditRecorder,
+	ttl time.Duration,
+	publicURL string,
+) *RecoveryService {
+	return &RecoveryService{
+		logger:     logger,
+		users:      users,
+		tokens:     tokens,
+		mailer:     mailer,
+		revoker:    revoker,
+		producer:   producer,
+		transactor: transactor,
+		hasher:     hasher,
+		policy:     policy,
+		audit:      audit,
+		ttl:        ttl,
+		publicURL:  publicURL,
+	}
+}
+
+// RequestPasswordReset Отправляет письмо со ссылкой сброса пароля. Для неизвестного
+// email ничего не делает и ошибку не возвращает, чтобы не раскрывать наличие аккаунта
+func (s *RecoveryService) RequestPasswordReset(ctx context.Context, email string) error {
+	const op = "internal.users.recovery.RequestPasswordReset"
+
+	log := s.logger.With(slog.String("op", op))
+
+	user, err := s.users.FindOneByEmail(ctx, NormalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			log.Info("Запрошен сброс пароля для неизвестного email")
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.SendPasswordReset(ctx, user); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SendPasswordReset Отправляет пользователю письмо со ссылкой сброса пароля
+func (s *RecoveryService) SendPasswordReset(ctx context.Context, user *repo.User) error {
+	const op = "internal.users.recovery.SendPasswordReset"
+
+	if user.Email == nil {
+		return fmt.Errorf("%s: %w", op, ErrEmailRequired)
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.tokens.CreateOneTimeToken(ctx, &repo.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   repo.PurposePasswordReset,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	link := fmt.Sprintf("%s/password/reset/confirm?token=%s", s.publicURL, url.QueryEscape(token))
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      *user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nДля сброса пароля перейдите по ссылке:\n%s\n\nСсылка действует %s. Если вы не запрашивали сброс, просто проигнорируйте это письмо.",
+			user.Login, link, s.ttl,
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Отправлено письмо для сброса пароля", slog.String("op", op), slog.Int("user_id", user.ID))
+	return nil
+}
+
+// ConfirmPasswordReset Устанавливает новый пароль по одноразовому токену
+// и завершает все сессии пользователя
+func (s *RecoveryService) ConfirmPasswordReset(ctx context.Context, dto ConfirmPasswordResetDTO) error {
+	const op = "internal.users.recovery.ConfirmPasswordReset"
+
+	log := s.logger.With(slog.String("op", op))
+
+	// токен ищется без использования: ошибка в новом пароле не должна сжигать ссылку
+	tokenHash := hashToken(dto.Token)
+	stored, err := s.tokens.FindOneTimeToken(ctx, tokenHash, repo.PurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, repo.ErrOneTimeTokenNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidResetToken)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := s.users.FindOneByID(ctx, stored.UserID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := policy.Join(s.policy.CheckPassword("new_password", dto.NewPassword, user.Login)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPassword, err := s.hasher.Hash(dto.NewPassword)
+	if err != nil {
+		log.Error("Ошибка хеширования пароля", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		locked, err := s.users.FindOneByIDForUpdate(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Login != user.Login {
+			// логин сменили после проверки пароля
+			if err := policy.Join(s.policy.CheckPassword("new_password", dto.NewPassword, locked.Login)); err != nil {
+				return err
+			}
+		}
+		user = locked
+
+		user.PasswordHash = hashedPassword
+		user.PasswordResetRequired = false
+		if user.VerifiedAt == nil {
+			// переход по ссылке из письма подтверждает владение адресом
+			now := time.Now()
+			user.VerifiedAt = &now
+		}
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := s.revoker.RevokeUserTokens(ctx, user.ID); err != nil {
+			return err
+		}
+
+		// токен используется последним: при любой ошибке выше транзакция
+		// откатится и ссылка останется действующей
+		if _, err := s.tokens.ConsumeOneTimeToken(ctx, tokenHash, repo.PurposePasswordReset); err != nil {
+			if errors.Is(err, repo.ErrOneTimeTokenNotFound) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+
+		return emitEvent(ctx, s.producer, events.TypeUserPasswordChanged, events.UserActor(user.ID), user.ID, events.UserPasswordChanged{
+			UserID: user.ID,
+			Login:  user.Login,
+			Method: "reset",
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.audit.Record(ctx, AuthEventPasswordChanged, user.ID, user.Login, map[string]any{"method": "reset"})
+
+	log.Info("Пароль сброшен", slog.Int("user_id", user.ID))
+	return nil
+}
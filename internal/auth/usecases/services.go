@@ -4,25 +4,67 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
 	"log/slog"
+	"strings"
+	"task-manager/internal/auth/policy"
 	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
 	"task-manager/pkg/logger/sl"
 	"time"
 )
 
 var (
-	ErrIncorrectCredentials = errors.New("неправильный логин или пароль")
+	ErrIncorrectCredentials  = errors.New("неправильный логин или пароль")
+	ErrEmailRequired         = errors.New("для регистрации нужен email")
+	ErrEmailNotVerified      = errors.New("email не подтвержден")
+	ErrAccountDisabled       = errors.New("аккаунт заблокирован")
+	ErrPasswordResetRequired = errors.New("требуется сброс пароля")
 )
 
 type UserService struct {
 	logger     *slog.Logger
 	repository RepositoryInterface
 	producer   Producer
+	transactor Transactor
+	revoker    TokenRevoker
+	hasher     PasswordHasher
+	policy     CredentialPolicy
+	audit      AuditRecorder
+	// dummyHash проверяется для несуществующего логина, чтобы время ответа
+	// не выдавало, есть ли такой пользователь
+	dummyHash string
+	// requireVerifiedEmail вход только с подтвержденным email
+	requireVerifiedEmail bool
 }
 
-func NewUserService(logger *slog.Logger, repo RepositoryInterface, producer Producer) *UserService {
-	return &UserService{repository: repo, logger: logger, producer: producer}
+func NewUserService(
+	logger *slog.Logger,
+	repo RepositoryInterface,
+	producer Producer,
+	transactor Transactor,
+	revoker TokenRevoker,
+	hasher PasswordHasher,
+	policy CredentialPolicy,
+	audit AuditRecorder,
+	requireVerifiedEmail bool,
+) *UserService {
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		logger.Error("Ошибка хеширования пароля", sl.Err(err))
+	}
+
+	return &UserService{
+		repository:           repo,
+		logger:               logger,
+		producer:             producer,
+		transactor:           transactor,
+		revoker:              revoker,
+		hasher:               hasher,
+		policy:               policy,
+		audit:                audit,
+		dummyHash:            dummyHash,
+		requireVerifiedEmail: requireVerifiedEmail,
+	}
 }
 
 // RegisterUser - создает пользователя с хешированным паролем
@@ -33,7 +75,17 @@ func (s *UserService) RegisterUser(ctx context.Context, dto UsersDTO) (*repo.Use
 		slog.String("op", op),
 		slog.String("login", dto.Login),
 	)
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(dto.Password), bcrypt.DefaultCost)
+	if s.requireVerifiedEmail && dto.Email == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrEmailRequired)
+	}
+	if err := policy.Join(
+		s.policy.CheckLogin(dto.Login),
+		s.policy.CheckPassword("password", dto.Password, dto.Login),
+	); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPassword, err := s.hasher.Hash(dto.Password)
 	if err != nil {
 		log.Error("Ошибка хеширования пароля", sl.Err(err))
 		return nil, fmt.Errorf("%s :%w", op, err)
@@ -41,24 +93,33 @@ func (s *UserService) RegisterUser(ctx context.Context, dto UsersDTO) (*repo.Use
 
 	user := &repo.User{
 		Login:        dto.Login,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
+	if dto.Email != "" {
+		email := NormalizeEmail(dto.Email)
+		user.Email = &email
+	}
 
-	err = s.repository.Create(ctx, user)
-	if err != nil {
-		if errors.Is(err, repo.ErrUserExists) {
-			return nil, fmt.Errorf("%s: %w", op, repo.ErrUserExists)
+	// пользователь и событие о регистрации сохраняются вместе: без события
+	// потребители не узнают о пользователе, а событие без пользователя ложное
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Create(ctx, user); err != nil {
+			return err
 		}
+
+		registered := events.UserRegistered{UserID: user.ID, Login: user.Login}
+		if user.Email != nil {
+			registered.Email = *user.Email
+		}
+		return emitEvent(ctx, s.producer, events.TypeUserRegistered, events.UserActor(user.ID), user.ID, registered)
+	})
+	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	message := fmt.Sprintf("Пользователь зарегестрирован")
-
-	if err := s.producer.SendMessage("key", message); err != nil {
-		log.Error("Ошибка отправки сообщения о зарегистрированном пользователе", sl.Err(err))
-	}
+	s.audit.Record(ctx, AuthEventRegistered, user.ID, user.Login, nil)
 
 	return user, nil
 }
@@ -71,6 +132,8 @@ func (s *UserService) AuthenticateUser(ctx context.Context, userDTO UsersDTO) (*
 	currentUser, err := s.repository.FindOne(ctx, userDTO.Login)
 	if err != nil {
 		if errors.Is(err, repo.ErrUserNotFound) {
+			_, _ = s.hasher.Verify(s.dummyHash, userDTO.Password)
+			s.loginFailed(ctx, 0, userDTO.Login, "unknown_login")
 			return nil, repo.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -78,13 +141,34 @@ func (s *UserService) AuthenticateUser(ctx context.Context, userDTO UsersDTO) (*
 
 	isValidHash := s.checkPasswordHash(currentUser, userDTO.Password)
 	if !isValidHash {
+		s.loginFailed(ctx, currentUser.ID, currentUser.Login, "bad_password")
 		return nil, fmt.Errorf("%s: %w", op, ErrIncorrectCredentials)
 	}
 
-	message := fmt.Sprintf("Пользователь %s успешно аутентифицирован", currentUser.Login)
-	if err := s.producer.SendMessage("key", message); err != nil {
-		log.Error("Ошибка отправки сообщения", sl.Err(err))
+	// проверяется после пароля, чтобы не раскрывать статус чужого аккаунта
+	if currentUser.DisabledAt != nil {
+		s.loginFailed(ctx, currentUser.ID, currentUser.Login, "account_disabled")
+		return nil, fmt.Errorf("%s: %w", op, ErrAccountDisabled)
+	}
+	if currentUser.PasswordResetRequired {
+		s.loginFailed(ctx, currentUser.ID, currentUser.Login, "password_reset_required")
+		return nil, fmt.Errorf("%s: %w", op, ErrPasswordResetRequired)
 	}
+	if s.requireVerifiedEmail && currentUser.VerifiedAt == nil {
+		s.loginFailed(ctx, currentUser.ID, currentUser.Login, "email_not_verified")
+		return nil, fmt.Errorf("%s: %w", op, ErrEmailNotVerified)
+	}
+
+	// только для аккаунта, которому вход разрешен
+	s.rehashIfNeeded(ctx, log, currentUser, userDTO.Password)
+
+	s.audit.Record(ctx, AuthEventLoginSucceeded, currentUser.ID, currentUser.Login, map[string]any{"method": "password"})
+
+	publishEvent(ctx, log, s.producer, events.TypeUserLoggedIn, events.UserActor(currentUser.ID), currentUser.ID, events.UserLoggedIn{
+		UserID: currentUser.ID,
+		Login:  currentUser.Login,
+		Method: "password",
+	})
 
 	return currentUser, nil
 
@@ -114,7 +198,17 @@ func (s *UserService) GetUserByID(ctx context.Context, id float64, password stri
 func (s *UserService) DeleteUser(ctx context.Context, user *repo.User) error {
 	const op = "internal.users.services.DeleteUser"
 
-	err := s.repository.Delete(ctx, user.ID)
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.revoker.RevokeUserTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := s.repository.Delete(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return emitEvent(ctx, s.producer, events.TypeUserDeleted, events.UserActor(user.ID), user.ID,
+			events.UserRef{UserID: user.ID, Login: user.Login})
+	})
 	if err != nil {
 		if errors.Is(err, repo.ErrUserNotFound) {
 			return fmt.Errorf("%s :%w", op, repo.ErrUserNotFound)
@@ -122,12 +216,147 @@ func (s *UserService) DeleteUser(ctx context.Context, user *repo.User) error {
 		return fmt.Errorf("%s :%w", op, err)
 	}
 
+	s.audit.Record(ctx, AuthEventAccountDeleted, user.ID, user.Login, nil)
+
 	return nil
 
 }
 
+// UpdateAccount Меняет логин и/или email пользователя после проверки текущего пароля
+func (s *UserService) UpdateAccount(ctx context.Context, id float64, dto UpdateAccountDTO) (*repo.User, error) {
+	const op = "internal.users.services.UpdateAccount"
+
+	if dto.Login != "" {
+		if err := policy.Join(s.policy.CheckLogin(dto.Login)); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	user, err := s.GetUserByID(ctx, id, dto.CurrentPassword)
+	if err != nil {
+		return nil, fmt.Errorf("%s :%w", op, err)
+	}
+
+	oldLogin := user.Login
+	if dto.Login != "" {
+		user.Login = dto.Login
+	}
+	emailChanged := false
+	if email := NormalizeEmail(dto.Email); email != "" && (user.Email == nil || *user.Email != email) {
+		// новый адрес нужно подтвердить заново
+		user.Email = &email
+		user.VerifiedAt = nil
+		emailChanged = true
+	}
+	err = s.saveAndRevoke(ctx, user, events.TypeUserUpdated, events.UserUpdated{
+		UserID:       user.ID,
+		OldLogin:     oldLogin,
+		Login:        user.Login,
+		EmailChanged: emailChanged,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s :%w", op, err)
+	}
+
+	return user, nil
+}
+
+// ChangePassword Меняет пароль пользователя после проверки текущего пароля
+func (s *UserService) ChangePassword(ctx context.Context, id float64, dto ChangePasswordDTO) (*repo.User, error) {
+	const op = "internal.users.services.ChangePassword"
+
+	log := s.logger.With(slog.String("op", op), slog.Int("user_id", int(id)))
+
+	user, err := s.GetUserByID(ctx, id, dto.CurrentPassword)
+	if err != nil {
+		return nil, fmt.Errorf("%s :%w", op, err)
+	}
+	if err := policy.Join(s.policy.CheckPassword("new_password", dto.NewPassword, user.Login)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hashedPassword, err := s.hasher.Hash(dto.NewPassword)
+	if err != nil {
+		log.Error("Ошибка хеширования пароля", sl.Err(err))
+		return nil, fmt.Errorf("%s :%w", op, err)
+	}
+
+	user.PasswordHash = hashedPassword
+	err = s.saveAndRevoke(ctx, user, events.TypeUserPasswordChanged, events.UserPasswordChanged{
+		UserID: user.ID,
+		Login:  user.Login,
+		Method: "change",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s :%w", op, err)
+	}
+
+	s.audit.Record(ctx, AuthEventPasswordChanged, user.ID, user.Login, map[string]any{"method": "change"})
+
+	return user, nil
+}
+
+// saveAndRevoke Сохраняет изменения аккаунта, завершает все его сессии и
+// записывает событие об изменении в одной транзакции
+func (s *UserService) saveAndRevoke(ctx context.Context, user *repo.User, eventType string, payload any) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Update(ctx, user); err != nil {
+			return err
+		}
+		if err := s.revoker.RevokeUserTokens(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return emitEvent(ctx, s.producer, eventType, events.UserActor(user.ID), user.ID, payload)
+	})
+}
+
+// loginFailed Записывает в журнал неудачный вход с причиной отказа
+func (s *UserService) loginFailed(ctx context.Context, userID int, login, reason string) {
+	s.audit.Record(ctx, AuthEventLoginFailed, userID, login, map[string]any{"reason": reason})
+}
+
+// NormalizeEmail Email хранится в нижнем регистре без пробелов по краям
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // CheckPassword - проверяет, совпадает ли пароль с хешем
 func (s *UserService) checkPasswordHash(user *repo.User, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-	return err == nil
+	ok, err := s.hasher.Verify(user.PasswordHash, password)
+	if err != nil {
+		s.logger.Error("Ошибка проверки хеша пароля", slog.Int("user_id", user.ID), sl.Err(err))
+		return false
+	}
+	return ok
+}
+
+// rehashIfNeeded Пересчитывает хеш, посчитанный устаревшим алгоритмом или параметрами.
+// Открытый пароль есть только при входе, поэтому обновление происходит здесь.
+// Меняется только хеш и только если он прежний: user прочитан до долгого
+// хеширования, и запись всей строки затерла бы сделанные за это время
+// блокировку, роль или смену пароля. Ошибка не мешает входу: хеш обновится в следующий раз
+func (s *UserService) rehashIfNeeded(ctx context.Context, log *slog.Logger, user *repo.User, password string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Error("Ошибка пересчета хеша пароля", sl.Err(err))
+		return
+	}
+
+	updated, err := s.repository.UpdatePasswordHash(ctx, user.ID, user.PasswordHash, hashedPassword)
+	if err != nil {
+		log.Error("Ошибка сохранения нового хеша пароля", sl.Err(err))
+		return
+	}
+	if !updated {
+		log.Info("Хеш пароля не обновлен, пароль сменился во время входа", slog.Int("user_id", user.ID))
+		return
+	}
+
+	user.PasswordHash = hashedPassword
+	log.Info("Хеш пароля обновлен", slog.Int("user_id", user.ID))
 }
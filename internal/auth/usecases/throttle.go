@@ -0,0 +1,244 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+var (
+	ErrTooManyLoginAttempts = errors.New("слишком много попыток входа")
+)
+
+// ThrottledError Вход временно запрещен, повторить можно через RetryAfter
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, повторите через %s", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// ThrottlePolicy Параметры защиты от перебора паролей
+type ThrottlePolicy struct {
+	// FreeFailures столько неудач подряд не вызывают задержки
+	FreeFailures int
+	// BackoffBase задержка после первой неудачи сверх FreeFailures, дальше удваивается
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// LoginMaxFailures после стольких неудач логин блокируется на Lockout
+	LoginMaxFailures int
+	// IPMaxFailures то же для адреса, с которого перебирают разные логины
+	IPMaxFailures int
+	Lockout       time.Duration
+	// Window неудачи старше окна не учитываются
+	Window time.Duration
+}
+
+// LoginThrottler Счетчики неудачных входов по логину и по IP с экспоненциальной
+// задержкой и временной блокировкой
+type LoginThrottler struct {
+	logger   *slog.Logger
+	store    LoginAttemptStore
+	producer Producer
+	audit    AuditRecorder
+	policy   ThrottlePolicy
+}
+
+func NewLoginThrottler(
+	logger *slog.Logger,
+	store LoginAttemptStore,
+	producer Producer,
+	audit AuditRecorder,
+	policy ThrottlePolicy,
+) *LoginThrottler {
+	return &LoginThrottler{logger: logger, store: store, producer: producer, audit: audit, policy: policy}
+}
+
+// LoginAttempt Попытка входа, учтенная Reserve. Завершается одним из Failure,
+// Success или Release
+type LoginAttempt struct {
+	login string
+	keys  []string
+	// failures счетчики по keys с учетом этой попытки
+	failures []int
+}
+
+// Reserve Учитывает попытку входа до проверки пароля. Возвращает *ThrottledError,
+// если попытку нужно отклонить не проверяя пароль, отклоненная попытка не учитывается
+func (t *LoginThrottler) Reserve(ctx context.Context, login, ip string) (*LoginAttempt, error) {
+	const op = "internal.users.throttle.Reserve"
+
+	now := time.Now()
+	attempt := &LoginAttempt{login: login}
+	limits := t.limits()
+	var worst *ThrottledError
+	for i, key := range t.keys(login, ip) {
+		attempts, err := t.store.ReserveLoginAttempt(ctx, key, now, t.policy.Window)
+		if err != nil {
+			t.release(ctx, attempt)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		attempt.keys = append(attempt.keys, key)
+		attempt.failures = append(attempt.failures, attempts.Failures+1)
+
+		throttled := t.throttled(attempts, now)
+		if throttled == nil && attempts.Failures >= limits[i] {
+			// лимит занят попытками, которые еще проверяются
+			throttled = &ThrottledError{RetryAfter: t.policy.BackoffBase}
+		}
+		if throttled != nil && (worst == nil || throttled.RetryAfter > worst.RetryAfter) {
+			worst = throttled
+		}
+	}
+
+	if worst != nil {
+		t.release(ctx, attempt)
+		return nil, worst
+	}
+	return attempt, nil
+}
+
+// Failure Отмечает попытку неудачной и при достижении порога блокирует логин или IP
+func (t *LoginThrottler) Failure(ctx context.Context, attempt *LoginAttempt) error {
+	const op = "internal.users.throttle.Failure"
+
+	now := time.Now()
+	limits := t.limits()
+	for i, key := range attempt.keys {
+		if err := t.store.RegisterLoginFailure(ctx, key, now); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if attempt.failures[i] < limits[i] {
+			continue
+		}
+
+		until := now.Add(t.policy.Lockout)
+		if err := t.store.LockLogin(ctx, key, until); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		t.lockoutEvent(ctx, attempt.login, key, attempt.failures[i], until)
+	}
+
+	return nil
+}
+
+// Success Сбрасывает счетчик логина. Попытка по IP только возвращается, иначе вход
+// в свой аккаунт позволял бы продолжать перебор чужих
+func (t *LoginThrottler) Success(ctx context.Context, attempt *LoginAttempt) error {
+	const op = "internal.users.throttle.Success"
+
+	if err := t.store.ResetLoginAttempts(ctx, attempt.keys[0]); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := t.store.ReleaseLoginAttempt(ctx, attempt.keys[1]); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Release Возвращает попытку, которая завершилась не из-за неверного пароля
+func (t *LoginThrottler) Release(ctx context.Context, attempt *LoginAttempt) error {
+	const op = "internal.users.throttle.Release"
+
+	for _, key := range attempt.keys {
+		if err := t.store.ReleaseLoginAttempt(ctx, key); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// RunCleanup Периодически удаляет устаревшие счетчики, пока не отменен ctx
+func (t *LoginThrottler) RunCleanup(ctx context.Context, interval time.Duration) {
+	const op = "internal.users.throttle.RunCleanup"
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.store.CleanupLoginAttempts(ctx, time.Now().Add(-t.policy.Window)); err != nil {
+				t.logger.Error("Ошибка очистки счетчиков входа", slog.String("op", op), sl.Err(err))
+			}
+		}
+	}
+}
+
+func (t *LoginThrottler) throttled(attempts repo.LoginAttempts, now time.Time) *ThrottledError {
+	if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+		return &ThrottledError{RetryAfter: attempts.LockedUntil.Sub(now), Locked: true}
+	}
+
+	extra := attempts.Failures - t.policy.FreeFailures
+	if extra <= 0 || attempts.LastFailureAt.Before(now.Add(-t.policy.Window)) {
+		return nil
+	}
+
+	delay := t.policy.BackoffMax
+	if extra <= 30 {
+		delay = min(t.policy.BackoffBase<<(extra-1), t.policy.BackoffMax)
+	}
+
+	if next := attempts.LastFailureAt.Add(delay); next.After(now) {
+		return &ThrottledError{RetryAfter: next.Sub(now)}
+	}
+	return nil
+}
+
+// release Возвращает попытки, учтенные до отказа в Reserve
+func (t *LoginThrottler) release(ctx context.Context, attempt *LoginAttempt) {
+	if err := t.Release(ctx, attempt); err != nil {
+		t.logger.Error("Ошибка возврата попытки входа", sl.Err(err))
+	}
+}
+
+func (t *LoginThrottler) lockoutEvent(ctx context.Context, login, key string, failures int, until time.Time) {
+	t.logger.Warn("Вход заблокирован после неудачных попыток",
+		slog.String("key", key),
+		slog.Int("failures", failures),
+		slog.Time("until", until),
+	)
+
+	t.audit.Record(ctx, AuthEventLoginLocked, 0, login, map[string]any{
+		"key":      key,
+		"failures": failures,
+		"until":    until.UTC().Format(time.RFC3339),
+	})
+
+	publishEvent(ctx, t.logger, t.producer, events.TypeUserLoginLocked, events.SystemActor(), 0, events.UserLoginLocked{
+		Login:    login,
+		Key:      key,
+		Failures: failures,
+		Until:    until.UTC(),
+	})
+}
+
+// keys Ключи счетчиков в том же порядке, что и limits
+func (t *LoginThrottler) keys(login, ip string) []string {
+	return []string{loginKey(login), "ip:" + ip}
+}
+
+func (t *LoginThrottler) limits() []int {
+	return []int{t.policy.LoginMaxFailures, t.policy.IPMaxFailures}
+}
+
+func loginKey(login string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
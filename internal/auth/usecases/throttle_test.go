@@ -0,0 +1,249 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"testing"
+	"time"
+)
+
+type stubProducer struct {
+	types []string
+}
+
+func (p *stubProducer) Publish(_ context.Context, event *events.Event) error {
+	p.types = append(p.types, event.Type)
+	return nil
+}
+
+type stubAudit struct {
+	keys []any
+}
+
+func (a *stubAudit) Record(_ context.Context, _ string, _ int, _ string, details map[string]any) {
+	a.keys = append(a.keys, details["key"])
+}
+
+func newTestThrottler(policy ThrottlePolicy) (*LoginThrottler, *stubAudit) {
+	audit := &stubAudit{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewLoginThrottler(log, repo.NewMemoryLoginAttempts(), &stubProducer{}, audit, policy), audit
+}
+
+func TestThrottled(t *testing.T) {
+	throttler, _ := newTestThrottler(ThrottlePolicy{
+		FreeFailures: 3,
+		BackoffBase:  time.Second,
+		BackoffMax:   time.Minute,
+		Window:       15 * time.Minute,
+	})
+
+	now := time.Now()
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	lockedUntil := func(d time.Duration) *time.Time {
+		until := now.Add(d)
+		return &until
+	}
+
+	tests := []struct {
+		name       string
+		attempts   repo.LoginAttempts
+		wantRetry  time.Duration
+		wantLocked bool
+	}{
+		{name: "нет неудач"},
+		{name: "бесплатные неудачи", attempts: repo.LoginAttempts{Failures: 3, LastFailureAt: now}},
+		{name: "первая задержка", attempts: repo.LoginAttempts{Failures: 4, LastFailureAt: now}, wantRetry: time.Second},
+		{name: "задержка удваивается", attempts: repo.LoginAttempts{Failures: 6, LastFailureAt: now}, wantRetry: 4 * time.Second},
+		{name: "часть задержки прошла", attempts: repo.LoginAttempts{Failures: 5, LastFailureAt: ago(time.Second)}, wantRetry: time.Second},
+		{name: "задержка прошла", attempts: repo.LoginAttempts{Failures: 4, LastFailureAt: ago(2 * time.Second)}},
+		{name: "задержка не больше максимума", attempts: repo.LoginAttempts{Failures: 10, LastFailureAt: now}, wantRetry: time.Minute},
+		// сдвиг больше 30 бит переполнил бы Duration
+		{name: "очень много неудач", attempts: repo.LoginAttempts{Failures: 100, LastFailureAt: now}, wantRetry: time.Minute},
+		{name: "неудачи вне окна", attempts: repo.LoginAttempts{Failures: 9, LastFailureAt: ago(time.Hour)}},
+		{
+			name:       "блокировка",
+			attempts:   repo.LoginAttempts{Failures: 0, LastFailureAt: now, LockedUntil: lockedUntil(5 * time.Minute)},
+			wantRetry:  5 * time.Minute,
+			wantLocked: true,
+		},
+		{
+			name:     "истекшая блокировка",
+			attempts: repo.LoginAttempts{Failures: 0, LastFailureAt: ago(time.Hour), LockedUntil: lockedUntil(-time.Minute)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := throttler.throttled(tt.attempts, now)
+			if tt.wantRetry == 0 {
+				if got != nil {
+					t.Errorf("throttled = %v, ожидалось nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("throttled = nil, ожидалась задержка %s", tt.wantRetry)
+			}
+			if got.RetryAfter != tt.wantRetry || got.Locked != tt.wantLocked {
+				t.Errorf("throttled = {%s, %v}, ожидалось {%s, %v}", got.RetryAfter, got.Locked, tt.wantRetry, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestThrottlerKeys(t *testing.T) {
+	throttler, _ := newTestThrottler(ThrottlePolicy{})
+
+	tests := []struct {
+		login string
+		ip    string
+		want  []string
+	}{
+		{login: "ivan", ip: "10.0.0.1", want: []string{"login:ivan", "ip:10.0.0.1"}},
+		// регистр и пробелы не дают обойти счетчик логина
+		{login: "  IVAN ", ip: "10.0.0.1", want: []string{"login:ivan", "ip:10.0.0.1"}},
+		{login: "ivan", ip: "::1", want: []string{"login:ivan", "ip:::1"}},
+	}
+	for _, tt := range tests {
+		if got := throttler.keys(tt.login, tt.ip); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("keys(%q, %q) = %v, ожидалось %v", tt.login, tt.ip, got, tt.want)
+		}
+	}
+}
+
+// lockoutPolicy Без задержек, чтобы проверять только пороги блокировки
+var lockoutPolicy = ThrottlePolicy{
+	LoginMaxFailures: 3,
+	IPMaxFailures:    5,
+	Lockout:          time.Minute,
+	Window:           time.Hour,
+}
+
+// fail Проходит Reserve и отмечает попытку неудачной
+func fail(t *testing.T, throttler *LoginThrottler, login, ip string) {
+	t.Helper()
+
+	ctx := context.Background()
+	attempt, err := throttler.Reserve(ctx, login, ip)
+	if err != nil {
+		t.Fatalf("Reserve(%q, %q): %v", login, ip, err)
+	}
+	if err := throttler.Failure(ctx, attempt); err != nil {
+		t.Fatalf("Failure: %v", err)
+	}
+}
+
+func wantLocked(t *testing.T, err error) {
+	t.Helper()
+
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || !throttled.Locked {
+		t.Fatalf("Reserve = %v, ожидалась блокировка", err)
+	}
+}
+
+func TestThrottlerLockout(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, throttler *LoginThrottler) []any
+	}{
+		{
+			name: "порог логина",
+			run: func(t *testing.T, throttler *LoginThrottler) []any {
+				for range lockoutPolicy.LoginMaxFailures {
+					fail(t, throttler, "ivan", "10.0.0.1")
+				}
+
+				_, err := throttler.Reserve(ctx, "Ivan", "10.0.0.2")
+				wantLocked(t, err)
+				if _, err := throttler.Reserve(ctx, "petr", "10.0.0.1"); err != nil {
+					t.Errorf("другой логин с того же IP: %v", err)
+				}
+				return []any{"login:ivan"}
+			},
+		},
+		{
+			name: "порог IP по разным логинам",
+			run: func(t *testing.T, throttler *LoginThrottler) []any {
+				for _, login := range []string{"a", "b", "c", "d", "e"} {
+					fail(t, throttler, login, "10.0.0.1")
+				}
+
+				_, err := throttler.Reserve(ctx, "new", "10.0.0.1")
+				wantLocked(t, err)
+				if _, err := throttler.Reserve(ctx, "new", "10.0.0.2"); err != nil {
+					t.Errorf("тот же логин с другого IP: %v", err)
+				}
+				return []any{"ip:10.0.0.1"}
+			},
+		},
+		{
+			name: "успешный вход сбрасывает только логин",
+			run: func(t *testing.T, throttler *LoginThrottler) []any {
+				fail(t, throttler, "ivan", "10.0.0.1")
+				fail(t, throttler, "ivan", "10.0.0.1")
+				attempt, err := throttler.Reserve(ctx, "ivan", "10.0.0.1")
+				if err != nil {
+					t.Fatalf("Reserve: %v", err)
+				}
+				if err := throttler.Success(ctx, attempt); err != nil {
+					t.Fatalf("Success: %v", err)
+				}
+
+				// у логина снова весь запас, у IP осталось 3 из 5
+				fail(t, throttler, "ivan", "10.0.0.1")
+				fail(t, throttler, "ivan", "10.0.0.1")
+				fail(t, throttler, "petr", "10.0.0.1")
+
+				_, err = throttler.Reserve(ctx, "sidr", "10.0.0.1")
+				wantLocked(t, err)
+				return []any{"ip:10.0.0.1"}
+			},
+		},
+		{
+			name: "одновременные попытки не превышают порог",
+			run: func(t *testing.T, throttler *LoginThrottler) []any {
+				// попытки еще проверяются и не завершены ни успехом, ни неудачей
+				var reserved []*LoginAttempt
+				for range lockoutPolicy.LoginMaxFailures {
+					attempt, err := throttler.Reserve(ctx, "ivan", "10.0.0.1")
+					if err != nil {
+						t.Fatalf("Reserve: %v", err)
+					}
+					reserved = append(reserved, attempt)
+				}
+
+				var throttled *ThrottledError
+				if _, err := throttler.Reserve(ctx, "ivan", "10.0.0.2"); !errors.As(err, &throttled) {
+					t.Fatalf("Reserve сверх порога = %v, ожидался отказ", err)
+				}
+
+				// отказ не занимает место: после возврата одной попытки вход снова возможен
+				if err := throttler.Release(ctx, reserved[0]); err != nil {
+					t.Fatalf("Release: %v", err)
+				}
+				if _, err := throttler.Reserve(ctx, "ivan", "10.0.0.2"); err != nil {
+					t.Errorf("Reserve после Release: %v", err)
+				}
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttler, audit := newTestThrottler(lockoutPolicy)
+
+			want := tt.run(t, throttler)
+			if !reflect.DeepEqual(audit.keys, want) {
+				t.Errorf("блокировки в журнале %v, ожидалось %v", audit.keys, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,475 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"log/slog"
+	"strings"
+	"task-manager/internal/auth/repo"
+	jwtissuer "task-manager/pkg/jwt"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("невалидный refresh-токен")
+	ErrRefreshTokenReused  = errors.New("refresh-токен использован повторно")
+	ErrInvalidAccessToken  = errors.New("невалидный access-токен")
+	ErrInvalidMFAToken     = errors.New("невалидный или истекший токен второго шага входа")
+)
+
+// tokenUseClaim Назначение промежуточного токена. У access-токенов claim отсутствует
+const (
+	tokenUseClaim = "token_use"
+	tokenUseMFA   = "mfa_pending"
+)
+
+const (
+	// sessionTouchInterval как часто обновляется время последней активности сессии
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength длиннее User-Agent обрезается перед сохранением
+	maxUserAgentLength = 512
+)
+
+// TokenPair Короткоживущий access-токен и ротируемый refresh-токен
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+type TokenService struct {
+	logger      *slog.Logger
+	users       RepositoryInterface
+	repository  RefreshTokenRepository
+	revocations RevocationRepository
+	sessions    SessionRepository
+	issuer      *jwtissuer.Issuer
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	mfaTTL      time.Duration
+}
+
+func NewTokenService(
+	logger *slog.Logger,
+	users RepositoryInterface,
+	repository RefreshTokenRepository,
+	revocations RevocationRepository,
+	sessions SessionRepository,
+	issuer *jwtissuer.Issuer,
+	accessTTL time.Duration,
+	refreshTTL time.Duration,
+	mfaTTL time.Duration,
+) *TokenService {
+	return &TokenService{
+		logger:      logger,
+		users:       users,
+		repository:  repository,
+		revocations: revocations,
+		sessions:    sessions,
+		issuer:      issuer,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+		mfaTTL:      mfaTTL,
+	}
+}
+
+// IssueTokens Выдает пару токенов при входе, открывая новое семейство refresh-токенов
+// и сессию с данными устройства
+func (s *TokenService) IssueTokens(ctx context.Context, user *repo.User, client ClientInfo) (*TokenPair, error) {
+	const op = "internal.users.tokens.IssueTokens"
+
+	if user.DisabledAt != nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrAccountDisabled)
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pair, jti, err := s.issue(ctx, user, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.sessions.CreateSession(ctx, &repo.Session{
+		ID:        familyID,
+		UserID:    user.ID,
+		UserAgent: truncateUserAgent(client.UserAgent),
+		IP:        client.IP,
+		AccessJTI: jti,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pair, nil
+}
+
+// IssueMFAToken Выдает после проверки пароля короткоживущий токен, который
+// годится только для ввода кода второго фактора
+func (s *TokenService) IssueMFAToken(ctx context.Context, user *repo.User) (string, time.Duration, error) {
+	const op = "internal.users.tokens.IssueMFAToken"
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims := map[string]interface{}{"user_id": user.ID, "jti": jti, tokenUseClaim: tokenUseMFA}
+	issuedAt, err := s.issuedAt(ctx, user.ID)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	_, token, err := s.issuer.IssueAt(claims, issuedAt, s.mfaTTL)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, s.mfaTTL, nil
+}
+
+// ParseMFAToken Проверяет токен второго шага входа и возвращает его вместе с пользователем
+func (s *TokenService) ParseMFAToken(ctx context.Context, tokenString string) (jwt.Token, int, error) {
+	const op = "internal.users.tokens.ParseMFAToken"
+
+	token, err := s.issuer.Parse(tokenString)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w: %w", op, ErrInvalidMFAToken, err)
+	}
+	if use, _ := token.PrivateClaims()[tokenUseClaim].(string); use != tokenUseMFA {
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+	}
+
+	revoked, err := s.IsRevoked(ctx, token)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+	}
+
+	userID, err := UserIDFromToken(token)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, ErrInvalidMFAToken)
+	}
+
+	return token, userID, nil
+}
+
+// RevokeMFAToken Делает токен второго шага одноразовым
+func (s *TokenService) RevokeMFAToken(ctx context.Context, token jwt.Token) error {
+	const op = "internal.users.tokens.RevokeMFAToken"
+
+	userID, err := UserIDFromToken(token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.revocations.RevokeToken(ctx, token.JwtID(), userID, token.Expiration()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Refresh Меняет refresh-токен на новую пару. Повторное предъявление уже
+// использованного токена означает его утечку, поэтому отзывается все семейство
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	const op = "internal.users.tokens.Refresh"
+
+	log := s.logger.With(slog.String("op", op))
+
+	stored, err := s.repository.FindRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repo.ErrRefreshTokenNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		return nil, s.revokeReused(ctx, log, op, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+	}
+
+	marked, err := s.repository.MarkRefreshTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !marked {
+		// токен успели использовать параллельным запросом
+		return nil, s.revokeReused(ctx, log, op, stored)
+	}
+
+	// пользователь перечитывается, чтобы новый токен получил актуальную роль,
+	// а заблокированный аккаунт не мог продлить сессию
+	user, err := s.users.FindOneByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidRefreshToken)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if user.DisabledAt != nil {
+		if err := s.revokeSession(ctx, stored.UserID, stored.FamilyID); err != nil {
+			log.Error("Ошибка отзыва сессии", sl.Err(err))
+		}
+		return nil, fmt.Errorf("%s: %w", op, ErrAccountDisabled)
+	}
+
+	pair, jti, err := s.issue(ctx, user, stored.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.sessions.RenewSession(ctx, stored.FamilyID, jti, time.Now().Add(s.refreshTTL)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pair, nil
+}
+
+// Logout Отзывает предъявленный access-токен и refresh-токены того же входа
+func (s *TokenService) Logout(ctx context.Context, token jwt.Token) error {
+	const op = "internal.users.tokens.Logout"
+
+	userID, err := UserIDFromToken(token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.revocations.RevokeToken(ctx, token.JwtID(), userID, token.Expiration()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if sid := SessionIDFromToken(token); sid != "" {
+		if err := s.revokeSession(ctx, userID, sid); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// ListSessions Действующие сессии пользователя
+func (s *TokenService) ListSessions(ctx context.Context, userID int) ([]repo.Session, error) {
+	const op = "internal.users.tokens.ListSessions"
+
+	sessions, err := s.sessions.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession Завершает сессию пользователя: ее refresh-токены отзываются,
+// а access-токены перестают проходить проверку отзыва
+func (s *TokenService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	const op = "internal.users.tokens.RevokeSession"
+
+	if err := s.sessions.RevokeSession(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.repository.RevokeRefreshFamily(ctx, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Сессия завершена", slog.String("op", op), slog.Int("user_id", userID), slog.String("session_id", sessionID))
+	return nil
+}
+
+// RevokeUserTokens Отзывает все access- и refresh-токены пользователя
+func (s *TokenService) RevokeUserTokens(ctx context.Context, userID int) error {
+	const op = "internal.users.tokens.RevokeUserTokens"
+
+	// iat в токене с точностью до секунды, поэтому отзываются все токены текущей
+	// секунды, а новые токены выпускаются не раньше отсечки (см. issuedAt)
+	cutoff := time.Now().UTC().Truncate(time.Second).Add(time.Second)
+	if err := s.revocations.RevokeAllUserTokens(ctx, userID, cutoff); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.revocations.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.sessions.RevokeUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Все токены пользователя отозваны", slog.String("op", op), slog.Int("user_id", userID))
+
+	return nil
+}
+
+// IsRevoked Проверяет токен по списку отзыва и по его сессии. Токены без jti выпущены
+// до появления отзыва и не имеют срока жизни, поэтому считаются отозванными
+func (s *TokenService) IsRevoked(ctx context.Context, token jwt.Token) (bool, error) {
+	const op = "internal.users.tokens.IsRevoked"
+
+	if token.JwtID() == "" {
+		return true, nil
+	}
+
+	userID, err := UserIDFromToken(token)
+	if err != nil {
+		return true, nil
+	}
+
+	revoked, err := s.revocations.IsTokenRevoked(ctx, token.JwtID(), userID, token.IssuedAt())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return true, nil
+	}
+
+	if sid := SessionIDFromToken(token); sid != "" {
+		active, err := s.sessions.TouchSession(ctx, sid, sessionTouchInterval)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+		return !active, nil
+	}
+
+	return false, nil
+}
+
+// SessionIDFromToken Сессия, в рамках которой выпущен access-токен
+func SessionIDFromToken(token jwt.Token) string {
+	sid, _ := token.PrivateClaims()["sid"].(string)
+	return sid
+}
+
+// IsAccessToken Отличает access-токен от промежуточного токена входа,
+// который не должен открывать доступ к защищенным методам
+func IsAccessToken(token jwt.Token) bool {
+	_, ok := token.PrivateClaims()[tokenUseClaim]
+	return !ok
+}
+
+// RoleFromToken Роль из claim role. Токены, выпущенные до появления ролей, считаются пользовательскими
+func RoleFromToken(token jwt.Token) string {
+	if role, ok := token.PrivateClaims()["role"].(string); ok && role != "" {
+		return role
+	}
+	return repo.RoleUser
+}
+
+// UserIDFromToken Достает идентификатор пользователя из claim user_id
+func UserIDFromToken(token jwt.Token) (int, error) {
+	userID, ok := token.PrivateClaims()["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return 0, ErrInvalidAccessToken
+	}
+	return int(userID), nil
+}
+
+func (s *TokenService) revokeReused(ctx context.Context, log *slog.Logger, op string, stored *repo.RefreshToken) error {
+	log.Warn("Повторное использование refresh-токена, семейство отозвано",
+		slog.Int("user_id", stored.UserID),
+		slog.String("family_id", stored.FamilyID),
+	)
+
+	if err := s.revokeSession(ctx, stored.UserID, stored.FamilyID); err != nil {
+		log.Error("Ошибка отзыва сессии", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrRefreshTokenReused)
+}
+
+// revokeSession Отзывает семейство refresh-токенов и сессию. Сессия может быть
+// уже отозвана или отсутствовать у входов, сделанных до появления сессий
+func (s *TokenService) revokeSession(ctx context.Context, userID int, sessionID string) error {
+	if err := s.repository.RevokeRefreshFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeSession(ctx, userID, sessionID); err != nil && !errors.Is(err, repo.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// issue Выпускает пару токенов в рамках семейства и возвращает jti access-токена
+func (s *TokenService) issue(ctx context.Context, user *repo.User, familyID string) (*TokenPair, string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// sid связывает access-токен с семейством refresh-токенов для выхода
+	claims := map[string]interface{}{"user_id": user.ID, "jti": jti, "sid": familyID, "role": user.Role}
+
+	issuedAt, err := s.issuedAt(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	_, accessToken, err := s.issuer.IssueAt(claims, issuedAt, s.accessTTL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.repository.CreateRefreshToken(ctx, &repo.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: s.accessTTL}, jti, nil
+}
+
+// issuedAt Время выпуска нового токена: сейчас, но не раньше отсечки отзыва.
+// Отсечка опережает текущее время меньше чем на секунду, что укладывается в
+// допустимое расхождение часов при проверке iat
+func (s *TokenService) issuedAt(ctx context.Context, userID int) (time.Time, error) {
+	now := time.Now().UTC()
+	cutoff, err := s.revocations.UserTokenCutoff(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cutoff.After(now) {
+		return cutoff, nil
+	}
+	return now, nil
+}
+
+// truncateUserAgent Обрезает User-Agent до размера колонки, не разрывая символы
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+	return strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+}
+
+// randomToken Возвращает n случайных байт в base64url
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken В базе хранится только sha256 от токена
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"task-manager/internal/auth/repo"
+	"task-manager/pkg/events"
+	"task-manager/pkg/mailer"
+	"time"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("ссылка для подтверждения email недействительна или устарела")
+)
+
+// VerificationService Подтверждение email по ссылке из письма
+type VerificationService struct {
+	logger     *slog.Logger
+	users      RepositoryInterface
+	tokens     OneTimeTokenRepository
+	mailer     mailer.Mailer
+	producer   Producer
+	transactor Transactor
+	ttl        time.Duration
+	publicURL  string
+}
+
+func NewVerificationService(
+	logger *slog.Logger,
+	users RepositoryInterface,
+	tokens OneTimeTokenRepository,
+	mailer mailer.Mailer,
+	producer Producer,
+	transactor Transactor,
+	ttl time.Duration,
+	publicURL string,
+) *VerificationService {
+	return &VerificationService{
+		logger:     logger,
+		users:      users,
+		tokens:     tokens,
+		mailer:     mailer,
+		producer:   producer,
+		transactor: transactor,
+		ttl:        ttl,
+		publicURL:  publicURL,
+	}
+}
+
+// SendVerification Отправляет письмо со ссылкой подтверждения на текущий email пользователя.
+// Предыдущие неиспользованные ссылки перестают действовать
+func (s *VerificationService) SendVerification(ctx context.Context, user *repo.User) error {
+	const op = "internal.users.verification.SendVerification"
+
+	if user.Email == nil || user.VerifiedAt != nil {
+		return nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.tokens.CreateOneTimeToken(ctx, &repo.OneTimeToken{
+		UserID:    user.ID,
+		Purpose:   repo.PurposeEmailVerification,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	link := fmt.Sprintf("%s/email/verify?token=%s", s.publicURL, url.QueryEscape(token))
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      *user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\nДля подтверждения email перейдите по ссылке:\n%s\n\nСсылка действует %s.",
+			user.Login, link, s.ttl,
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Отправлено письмо для подтверждения email", slog.String("op", op), slog.Int("user_id", user.ID))
+	return nil
+}
+
+// ResendVerification Повторно отправляет ссылку по email. Для неизвестного или уже
+// подтвержденного адреса ничего не делает, чтобы не раскрывать наличие аккаунта
+func (s *VerificationService) ResendVerification(ctx context.Context, email string) error {
+	const op = "internal.users.verification.ResendVerification"
+
+	user, err := s.users.FindOneByEmail(ctx, NormalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			s.logger.Info("Запрошено подтверждение для неизвестного email", slog.String("op", op))
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.SendVerification(ctx, user); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConfirmEmail Отмечает email подтвержденным по одноразовому токену
+func (s *VerificationService) ConfirmEmail(ctx context.Context, token string) error {
+	const op = "internal.users.verification.ConfirmEmail"
+
+	log := s.logger.With(slog.String("op", op))
+
+	stored, err := s.tokens.ConsumeOneTimeToken(ctx, hashToken(token), repo.PurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, repo.ErrOneTimeTokenNotFound) {
+			return fmt.Errorf("%s: %w", op, ErrInvalidVerificationToken)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := s.users.FindOneByID(ctx, stored.UserID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if user.VerifiedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	user.VerifiedAt = &now
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+
+		verified := events.UserEmailVerified{UserID: user.ID, Login: user.Login}
+		if user.Email != nil {
+			verified.Email = *user.Email
+		}
+		return emitEvent(ctx, s.producer, events.TypeUserEmailVerified, events.UserActor(user.ID), user.ID, verified)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("Email подтвержден", slog.Int("user_id", user.ID))
+	return nil
+}
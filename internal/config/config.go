@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,20 +19,159 @@ type DatabaseConfig struct {
 }
 
 type GRPCServer struct {
-	Port     int
+	Port int
+	// TokenTTL время жизни access-токена
 	TokenTTL time.Duration
+	// RefreshTokenTTL время жизни refresh-токена
+	RefreshTokenTTL time.Duration
+	// PublicMethods методы и префиксы сервисов, доступные без токена
+	PublicMethods []string
+	// AdminMethods методы и префиксы сервисов, доступные только администраторам
+	AdminMethods []string
 }
 
 type HTTPServer struct {
-	Addr         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Addr string
+	// PublicURL адрес сервиса для ссылок в письмах
+	PublicURL string
+	// TrustProxyHeaders брать адрес клиента из X-Forwarded-For/X-Real-IP.
+	// Включать только за своим прокси, иначе адрес подделывается заголовком
+	TrustProxyHeaders bool
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+}
+
+// JWTKey Ключ подписи токенов. Material - секрет для HS256 или PEM-ключ для RS256/EdDSA.
+// Для ключей, которыми только проверяют старые токены, достаточно публичного PEM
+type JWTKey struct {
+	ID       string
+	Material []byte
+}
+
+type JWT struct {
+	// Algorithm HS256, RS256 или EdDSA
+	Algorithm string
+	// SigningKeyID kid ключа, которым подписываются новые токены
+	SigningKeyID string
+	// Keys все действующие ключи, токены принимаются с любым из них
+	Keys   []JWTKey
+	Issuer string
+}
+
+type Mail struct {
+	// MailDriver smtp или file
+	MailDriver   string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	MailFrom     string
+	// MailDir каталог для писем драйвера file
+	MailDir string
+}
+
+type Auth struct {
+	// MFATokenTTL время на ввод кода второго фактора после пароля
+	MFATokenTTL          time.Duration
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	// RequireEmailVerification запрещает вход до подтверждения email
+	// и делает email обязательным при регистрации
+	RequireEmailVerification bool
+}
+
+// PasswordHashing Параметры хеширования паролей. Смена параметров или алгоритма
+// применяется к старым паролям при следующем успешном входе
+type PasswordHashing struct {
+	// PasswordHashAlgorithm argon2id или bcrypt
+	PasswordHashAlgorithm string
+	BcryptCost            int
+	// Argon2Memory в КиБ
+	Argon2Memory     uint32
+	Argon2Time       uint32
+	Argon2Threads    uint8
+	Argon2SaltLength uint32
+	Argon2KeyLength  uint32
+}
+
+// CredentialPolicy Требования к логинам и паролям
+type CredentialPolicy struct {
+	LoginMinLength int
+	LoginMaxLength int
+	// LoginAllowedSymbols символы, разрешенные в логине помимо букв и цифр
+	LoginAllowedSymbols string
+	PasswordMinLength   int
+	// PasswordMaxLength в байтах
+	PasswordMaxLength int
+	// PasswordMinClasses сколько видов символов (строчные, заглавные, цифры, прочие) обязательно
+	PasswordMinClasses int
+	// PasswordDenyListFile файл с запрещенными паролями по одному в строке,
+	// дополняет встроенный список распространенных паролей
+	PasswordDenyListFile string
+}
+
+// BootstrapAdmin Первый администратор, создается при запуске, если администраторов еще нет.
+// Пустой логин отключает создание
+type BootstrapAdmin struct {
+	BootstrapAdminLogin    string
+	BootstrapAdminPassword string
+	BootstrapAdminEmail    string
+}
+
+// OIDC Вход через внешнего OpenID Connect провайдера. Пустой OIDCIssuerURL отключает вход
+type OIDC struct {
+	// OIDCProviderName имя провайдера, под которым хранятся внешние учетные записи
+	OIDCProviderName string
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	// OIDCRedirectURL адрес /oidc/callback, зарегистрированный у провайдера
+	OIDCRedirectURL string
+	OIDCScopes      []string
+	// OIDCAutoProvision создавать аккаунт при первом входе неизвестного пользователя
+	OIDCAutoProvision bool
+	// OIDCStateTTL время на прохождение входа у провайдера
+	OIDCStateTTL time.Duration
+}
+
+// LoginThrottle Защита /login от перебора паролей
+type LoginThrottle struct {
+	// LoginAttemptsStore memory для одного экземпляра или postgres для нескольких
+	LoginAttemptsStore string
+	LoginFreeFailures  int
+	LoginBackoffBase   time.Duration
+	LoginBackoffMax    time.Duration
+	LoginMaxFailures   int
+	IPMaxFailures      int
+	LoginLockout       time.Duration
+	LoginFailureWindow time.Duration
 }
 
 type Producer struct {
 	Brokers []string
 	Topic   string
+	// EventFormat формат событий: json или protobuf
+	EventFormat string
+}
+
+// Consumer Чтение событий группой потребителей. Пустая ConsumerGroup
+// отключает чтение, пустой ConsumerTopics - читается топик продюсера
+type Consumer struct {
+	ConsumerGroup     string
+	ConsumerTopics    []string
+	ConsumerRetryBase time.Duration
+	ConsumerRetryMax  time.Duration
+}
+
+// Outbox Отправка событий из таблицы outbox в Kafka
+type Outbox struct {
+	OutboxRelayInterval   time.Duration
+	OutboxBatchSize       int
+	OutboxBackoffBase     time.Duration
+	OutboxBackoffMax      time.Duration
+	OutboxRetention       time.Duration
+	OutboxCleanupInterval time.Duration
 }
 
 type Config struct {
@@ -39,7 +179,17 @@ type Config struct {
 	DatabaseConfig
 	HTTPServer
 	Producer
+	Consumer
+	Outbox
 	GRPCServer
+	JWT JWT
+	Mail
+	Auth
+	LoginThrottle
+	PasswordHashing
+	CredentialPolicy
+	BootstrapAdmin
+	OIDC
 }
 
 // New Создает и возвращает сущность конфига
@@ -47,8 +197,9 @@ func New() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("No env file")
 	}
+	env := getEnv("ENV", "local")
 	return &Config{
-		getEnv("ENV", "local"),
+		env,
 		DatabaseConfig{
 			DbName:        getEnv("POSTGRES_DB", "postgres_db"),
 			DbUser:        getEnv("POSTGRES_USER", "postgres"),
@@ -58,20 +209,159 @@ func New() *Config {
 			DbMaxAttempts: getEnvInt("POSTGRES_MAX_ATTEMPTS", 5),
 		},
 		HTTPServer{
-			Addr:         getEnv("HTTP_ADDR", "localhost:8082"),
-			ReadTimeout:  10 * time.Second,
-			WriteTimeout: 10 * time.Second,
-			IdleTimeout:  40 * time.Second,
+			Addr:              getEnv("HTTP_ADDR", "localhost:8082"),
+			PublicURL:         getEnv("PUBLIC_URL", "http://localhost:8082"),
+			TrustProxyHeaders: getEnvBool("TRUST_PROXY_HEADERS", false),
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       40 * time.Second,
 		},
 		Producer{
-			Brokers: []string{"localhost:9092"},
-			Topic:   getEnv("KAFKA_TOPIC", "log-topic"),
+			Brokers:     []string{"localhost:9092"},
+			Topic:       getEnv("KAFKA_TOPIC", "log-topic"),
+			EventFormat: getEnv("KAFKA_EVENT_FORMAT", "json"),
+		},
+		Consumer{
+			ConsumerGroup:     getEnv("KAFKA_CONSUMER_GROUP", ""),
+			ConsumerTopics:    getEnvList("KAFKA_CONSUMER_TOPICS", nil),
+			ConsumerRetryBase: getEnvDuration("KAFKA_CONSUMER_RETRY_BASE", time.Second),
+			ConsumerRetryMax:  getEnvDuration("KAFKA_CONSUMER_RETRY_MAX", time.Minute),
+		},
+		Outbox{
+			OutboxRelayInterval:   getEnvDuration("OUTBOX_RELAY_INTERVAL", time.Second),
+			OutboxBatchSize:       getEnvInt("OUTBOX_BATCH_SIZE", 100),
+			OutboxBackoffBase:     getEnvDuration("OUTBOX_BACKOFF_BASE", time.Second),
+			OutboxBackoffMax:      getEnvDuration("OUTBOX_BACKOFF_MAX", 5*time.Minute),
+			OutboxRetention:       getEnvDuration("OUTBOX_RETENTION", 7*24*time.Hour),
+			OutboxCleanupInterval: getEnvDuration("OUTBOX_CLEANUP_INTERVAL", time.Hour),
 		},
 		GRPCServer{
-			Port:     getEnvInt("GRPC_PORT", 44044),
-			TokenTTL: 10 * time.Minute,
+			Port:            getEnvInt("GRPC_PORT", 44044),
+			TokenTTL:        getEnvDuration("TOKEN_TTL", 10*time.Minute),
+			RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			PublicMethods: getEnvList("GRPC_PUBLIC_METHODS", []string{
+				"/grpc.reflection.v1.ServerReflection/",
+				"/grpc.reflection.v1alpha.ServerReflection/",
+				"/grpc.health.v1.Health/",
+			}),
+			// категории общие для всех пользователей, менять их может только администратор
+			AdminMethods: getEnvList("GRPC_ADMIN_METHODS", []string{
+				"/task.TaskCategory/CreateTaskCategory",
+				"/task.TaskCategory/UpdateTaskCategory",
+				"/task.TaskCategory/DeleteTaskCategory",
+			}),
+		},
+		loadJWT(env),
+		Mail{
+			MailDriver:   loadMailDriver(env),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvInt("SMTP_PORT", 587),
+			SMTPUser:     getEnv("SMTP_USER", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			MailFrom:     getEnv("MAIL_FROM", "task-manager@localhost"),
+			MailDir:      getEnv("MAIL_DIR", "tmp/mail"),
+		},
+		Auth{
+			MFATokenTTL:              getEnvDuration("MFA_TOKEN_TTL", 5*time.Minute),
+			PasswordResetTTL:         getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+			EmailVerificationTTL:     getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		},
+		LoginThrottle{
+			LoginAttemptsStore: getEnv("LOGIN_ATTEMPTS_STORE", "memory"),
+			LoginFreeFailures:  getEnvInt("LOGIN_FREE_FAILURES", 3),
+			LoginBackoffBase:   getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+			LoginBackoffMax:    getEnvDuration("LOGIN_BACKOFF_MAX", time.Minute),
+			LoginMaxFailures:   getEnvInt("LOGIN_MAX_FAILURES", 10),
+			IPMaxFailures:      getEnvInt("IP_MAX_FAILURES", 50),
+			LoginLockout:       getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+			LoginFailureWindow: getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		},
+		PasswordHashing{
+			PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			BcryptCost:            getEnvInt("BCRYPT_COST", 12),
+			Argon2Memory:          uint32(getEnvInt("ARGON2_MEMORY_KIB", 64*1024)),
+			Argon2Time:            uint32(getEnvInt("ARGON2_TIME", 3)),
+			Argon2Threads:         uint8(getEnvInt("ARGON2_THREADS", 2)),
+			Argon2SaltLength:      16,
+			Argon2KeyLength:       32,
+		},
+		CredentialPolicy{
+			LoginMinLength:       getEnvInt("LOGIN_MIN_LENGTH", 3),
+			LoginMaxLength:       getEnvInt("LOGIN_MAX_LENGTH", 32),
+			LoginAllowedSymbols:  getEnv("LOGIN_ALLOWED_SYMBOLS", "._-"),
+			PasswordMinLength:    getEnvInt("PASSWORD_MIN_LENGTH", 10),
+			PasswordMaxLength:    getEnvInt("PASSWORD_MAX_LENGTH", 128),
+			PasswordMinClasses:   getEnvInt("PASSWORD_MIN_CLASSES", 2),
+			PasswordDenyListFile: getEnv("PASSWORD_DENYLIST_FILE", ""),
+		},
+		BootstrapAdmin{
+			BootstrapAdminLogin:    getEnv("BOOTSTRAP_ADMIN_LOGIN", ""),
+			BootstrapAdminPassword: strings.TrimSpace(string(getEnvOrFile("BOOTSTRAP_ADMIN_PASSWORD"))),
+			BootstrapAdminEmail:    getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
 		},
+		OIDC{
+			OIDCProviderName:  getEnv("OIDC_PROVIDER_NAME", "sso"),
+			OIDCIssuerURL:     strings.TrimSuffix(getEnv("OIDC_ISSUER_URL", ""), "/"),
+			OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+			OIDCClientSecret:  strings.TrimSpace(string(getEnvOrFile("OIDC_CLIENT_SECRET"))),
+			OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", getEnv("PUBLIC_URL", "http://localhost:8082")+"/oidc/callback"),
+			OIDCScopes:        getEnvList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			OIDCAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", true),
+			OIDCStateTTL:      getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		},
+	}
+}
+
+// loadMailDriver Драйвер file пишет письма со ссылками на диск и разрешен только при ENV=local
+func loadMailDriver(env string) string {
+	driver := getEnv("MAIL_DRIVER", "file")
+	switch driver {
+	case "smtp":
+	case "file":
+		if env != "local" {
+			log.Fatal("MAIL_DRIVER=file допустим только при ENV=local, задайте MAIL_DRIVER=smtp")
+		}
+	default:
+		log.Fatalf("Неизвестный MAIL_DRIVER %q, ожидается smtp или file", driver)
+	}
+	return driver
+}
+
+// loadJWT Загружает ключи подписи. Каждый ключ из JWT_KEY_IDS читается из JWT_KEY_<KID>
+// или из файла JWT_KEY_<KID>_FILE. Без JWT_KEY_IDS используется один ключ JWT_SECRET
+func loadJWT(env string) JWT {
+	cnf := JWT{
+		Algorithm: getEnv("JWT_ALGORITHM", "HS256"),
+		Issuer:    getEnv("JWT_ISSUER", "task-manager"),
+	}
+
+	ids := getEnvList("JWT_KEY_IDS", nil)
+	if len(ids) == 0 {
+		material := getEnvOrFile("JWT_SECRET")
+		if material == nil {
+			if env != "local" {
+				log.Fatal("Не задан ключ подписи токенов JWT_SECRET")
+			}
+			log.Print("JWT_SECRET не задан, используется небезопасный ключ для локальной разработки")
+			material = []byte("secret")
+		}
+		cnf.Keys = []JWTKey{{ID: "default", Material: material}}
+		cnf.SigningKeyID = "default"
+		return cnf
+	}
+
+	for _, id := range ids {
+		key := "JWT_KEY_" + envSuffix(id)
+		material := getEnvOrFile(key)
+		if material == nil {
+			log.Fatalf("Не задан ключ подписи токенов %s", key)
+		}
+		cnf.Keys = append(cnf.Keys, JWTKey{ID: id, Material: material})
 	}
+	cnf.SigningKeyID = getEnv("JWT_SIGNING_KEY_ID", ids[0])
+
+	return cnf
 }
 
 // getEnv Достает из файла .env значение переменной среды типа String, если такого нет, возвращает стандартное значение
@@ -94,3 +384,73 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvBool Достает из файла .env значение переменной среды типа Bool, если такого нет, возвращает стандартное значение
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Ошибка конвертации %s: %v. Используется значение по умолчанию: %t", key, err, defaultValue)
+			return defaultValue
+		}
+		return boolValue
+	}
+	return defaultValue
+}
+
+// getEnvDuration Достает из файла .env длительность вида "10m", "720h", если такой нет, возвращает стандартное значение
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Ошибка конвертации %s: %v. Используется значение по умолчанию: %s", key, err, defaultValue)
+			return defaultValue
+		}
+		return duration
+	}
+	return defaultValue
+}
+
+// getEnvList Достает из файла .env список значений через запятую, если такого нет, возвращает стандартное значение
+func getEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvOrFile Достает значение из переменной key или из файла, путь к которому лежит в key_FILE
+func getEnvOrFile(key string) []byte {
+	if value, exists := os.LookupEnv(key); exists {
+		return []byte(value)
+	}
+	if path, exists := os.LookupEnv(key + "_FILE"); exists {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Ошибка чтения %s из %s: %v", key, path, err)
+		}
+		return data
+	}
+	return nil
+}
+
+// envSuffix Приводит идентификатор к виду, допустимому в имени переменной среды
+func envSuffix(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, id)
+}
@@ -12,5 +12,6 @@ type Task struct {
 	IsCompleted  bool            `json:"is_completed"`
 	CreatedAt    time.Time       `json:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at"`
+	UserID       int             `json:"user_id"`
 	TaskCategory tc.TaskCategory `json:"task_category"`
 }
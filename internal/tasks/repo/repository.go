@@ -2,16 +2,47 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"log/slog"
 	"task-manager/pkg/clients/posgresql"
 )
 
+var (
+	ErrTaskNotFound     = errors.New("задача не найдена")
+	ErrTaskForbidden    = errors.New("задача принадлежит другому пользователю")
+	ErrCategoryNotFound = errors.New("категория задачи не найдена")
+)
+
+// wrapError — вспомогательная функция для обработки ошибок
+func wrapError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("%s: %w", op, ErrTaskNotFound)
+	case errors.As(err, &pgErr):
+		switch {
+		// остальные внешние ключи, например на users, категорией не являются
+		case pgErr.Code == "23503" && pgErr.ConstraintName == "tasks_category_id_fkey":
+			return fmt.Errorf("%s: %w", op, ErrCategoryNotFound)
+		default:
+			return fmt.Errorf("%s: %s: %w", op, pgErr.Code, err)
+		}
+	default:
+		return fmt.Errorf("%s: %w", op, err)
+	}
+}
+
 type RepositoryInterface interface {
-	Create(ctx context.Context, task Task) (string, error)
-	FindAll(ctx context.Context) ([]Task, error)
-	FindOne(ctx context.Context, id int) (Task, error)
+	Create(ctx context.Context, task Task) (int, error)
+	FindAll(ctx context.Context, userID int) ([]Task, error)
+	FindOne(ctx context.Context, id int, userID int) (Task, error)
+	FindOneForUpdate(ctx context.Context, id int, userID int) (Task, error)
 	Update(ctx context.Context, task Task) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int, userID int) error
+	CategoryExists(ctx context.Context, categoryID int) (bool, error)
 }
 
 type repository struct {
@@ -19,29 +50,192 @@ type repository struct {
 	logger   *slog.Logger
 }
 
-func (r *repository) Create(ctx context.Context, task Task) (string, error) {
-	//TODO implement me
-	panic("implement me")
+// conn Соединение для запроса: транзакция, если она открыта в ctx, иначе пул
+func (r *repository) conn(ctx context.Context) posgresql.DBClient {
+	return posgresql.Conn(ctx, r.dbClient)
 }
 
-func (r *repository) FindAll(ctx context.Context) ([]Task, error) {
-	//TODO implement me
-	panic("implement me")
+const selectTask = `
+	SELECT t.id, t.title, COALESCE(t.description, ''), t.is_completed, t.created_at, t.updated_at,
+	       t.user_id, COALESCE(c.id, 0), COALESCE(c.title, '')
+	FROM tasks t
+	LEFT JOIN tasks_categories c ON c.id = t.category_id
+`
+
+func (r *repository) Create(ctx context.Context, task Task) (int, error) {
+	const op = "tasks.repo.Create"
+
+	stmt := `
+		INSERT INTO tasks (title, description, is_completed, category_id, user_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+		`
+	var id int
+	err := r.conn(ctx).QueryRow(
+		ctx, stmt, task.Title, task.Description, task.IsCompleted, nullableID(task.TaskCategory.ID), task.UserID,
+	).Scan(&id)
+	if err != nil {
+		return 0, wrapError(op, err)
+	}
+
+	return id, nil
 }
 
-func (r *repository) FindOne(ctx context.Context, id int) (Task, error) {
-	//TODO implement me
-	panic("implement me")
+func (r *repository) FindAll(ctx context.Context, userID int) ([]Task, error) {
+	const op = "tasks.repo.FindAll"
+
+	stmt := selectTask + `
+	WHERE t.user_id = $1
+	ORDER BY t.id
+`
+	rows, err := r.conn(ctx).Query(ctx, stmt, userID)
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
+	defer rows.Close()
+
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, wrapError(op, err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError(op, err)
+	}
+
+	return tasks, nil
+}
+
+func (r *repository) FindOne(ctx context.Context, id int, userID int) (Task, error) {
+	const op = "tasks.repo.FindOne"
+
+	stmt := selectTask + `
+	WHERE t.id = $1
+`
+	task, err := scanTask(r.conn(ctx).QueryRow(ctx, stmt, id))
+	if err != nil {
+		return Task{}, wrapError(op, err)
+	}
+	if task.UserID != userID {
+		return Task{}, fmt.Errorf("%s: %w", op, ErrTaskForbidden)
+	}
+
+	return task, nil
+}
+
+// FindOneForUpdate Как FindOne, но блокирует строку задачи до конца транзакции из ctx
+func (r *repository) FindOneForUpdate(ctx context.Context, id int, userID int) (Task, error) {
+	const op = "tasks.repo.FindOneForUpdate"
+
+	stmt := selectTask + `
+	WHERE t.id = $1
+	FOR UPDATE OF t
+`
+	task, err := scanTask(r.conn(ctx).QueryRow(ctx, stmt, id))
+	if err != nil {
+		return Task{}, wrapError(op, err)
+	}
+	if task.UserID != userID {
+		return Task{}, fmt.Errorf("%s: %w", op, ErrTaskForbidden)
+	}
+
+	return task, nil
 }
 
 func (r *repository) Update(ctx context.Context, task Task) error {
-	//TODO implement me
-	panic("implement me")
+	const op = "tasks.repo.Update"
+
+	stmt := `
+	UPDATE tasks
+	SET title = $1, description = $2, is_completed = $3, category_id = $4, updated_at = NOW()
+	WHERE id = $5 AND user_id = $6
+`
+	pgTag, err := r.conn(ctx).Exec(
+		ctx, stmt, task.Title, task.Description, task.IsCompleted, nullableID(task.TaskCategory.ID), task.ID, task.UserID,
+	)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if pgTag.RowsAffected() == 0 {
+		return r.checkOwner(ctx, op, task.ID, task.UserID)
+	}
+
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, id int, userID int) error {
+	const op = "tasks.repo.Delete"
+
+	query := `
+	DELETE
+	FROM tasks
+	WHERE id = $1 AND user_id = $2
+`
+	pgTag, err := r.conn(ctx).Exec(ctx, query, id, userID)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if pgTag.RowsAffected() == 0 {
+		return r.checkOwner(ctx, op, id, userID)
+	}
+
+	return nil
 }
 
-func (r *repository) Delete(ctx context.Context, id int) error {
-	//TODO implement me
-	panic("implement me")
+func (r *repository) CategoryExists(ctx context.Context, categoryID int) (bool, error) {
+	const op = "tasks.repo.CategoryExists"
+
+	var exists bool
+	err := r.conn(ctx).QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM tasks_categories WHERE id = $1)`, categoryID).Scan(&exists)
+	if err != nil {
+		return false, wrapError(op, err)
+	}
+
+	return exists, nil
+}
+
+// checkOwner Вызывается, когда запрос с фильтром по user_id не затронул ни одной строки,
+// и определяет, отсутствует ли задача или принадлежит другому пользователю
+func (r *repository) checkOwner(ctx context.Context, op string, id int, userID int) error {
+	var ownerID *int
+	err := r.conn(ctx).QueryRow(ctx, `SELECT user_id FROM tasks WHERE id = $1`, id).Scan(&ownerID)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if ownerID == nil || *ownerID != userID {
+		return fmt.Errorf("%s: %w", op, ErrTaskForbidden)
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrTaskNotFound)
+}
+
+func scanTask(row pgx.Row) (Task, error) {
+	var task Task
+	var userID *int
+
+	err := row.Scan(
+		&task.ID, &task.Title, &task.Description, &task.IsCompleted, &task.CreatedAt, &task.UpdatedAt,
+		&userID, &task.TaskCategory.ID, &task.TaskCategory.Title,
+	)
+	if err != nil {
+		return Task{}, err
+	}
+	if userID != nil {
+		task.UserID = *userID
+	}
+
+	return task, nil
+}
+
+// nullableID Превращает нулевой идентификатор категории в NULL
+func nullableID(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
 }
 
 func NewRepository(dbClient posgresql.DBClient, logger *slog.Logger) RepositoryInterface {
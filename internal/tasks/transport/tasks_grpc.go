@@ -1 +1,202 @@
 package transport
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log/slog"
+	authgrpc "task-manager/internal/auth/transport/transport_grpc"
+	"task-manager/internal/tasks/repo"
+	"task-manager/internal/tasks/usecases"
+	"task-manager/pkg/logger/sl"
+
+	tmv1 "github.com/dovgalb/taskmanager_proto/gen/go/task_manager"
+)
+
+type gRPCServerApi struct {
+	tmv1.UnimplementedTaskServer
+	log     *slog.Logger
+	service *usecases.TaskService
+}
+
+func Register(gRPC *grpc.Server, log *slog.Logger, service *usecases.TaskService) {
+	tmv1.RegisterTaskServer(gRPC, &gRPCServerApi{log: log, service: service})
+}
+
+func (tm *gRPCServerApi) CreateTask(ctx context.Context, request *tmv1.CreateTaskRequest) (*tmv1.TaskResponse, error) {
+	const op = "internal.tasks.grpc.CreateTask"
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := tm.service.CreateTask(ctx, userID, usecases.CreateTaskDTO{
+		Title:       request.GetTitle(),
+		Description: request.GetDescription(),
+		IsCompleted: request.GetIsCompleted(),
+		CategoryID:  int(request.GetTaskCategoryId()),
+	})
+	if err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return toResponse(task), nil
+}
+
+func (tm *gRPCServerApi) ReadTask(ctx context.Context, request *tmv1.ReadTaskRequest) (*tmv1.TaskResponse, error) {
+	const op = "internal.tasks.grpc.ReadTask"
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.GetTaskId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "task_id обязателен")
+	}
+
+	task, err := tm.service.GetTask(ctx, userID, int(request.GetTaskId()))
+	if err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return toResponse(task), nil
+}
+
+// UpdateTask Обновляет поля из update_mask, пустая маска означает полное обновление.
+// Маска из одного is_completed выполняет или переоткрывает задачу
+func (tm *gRPCServerApi) UpdateTask(ctx context.Context, request *tmv1.UpdateTaskRequest) (*tmv1.TaskResponse, error) {
+	const op = "internal.tasks.grpc.UpdateTask"
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := int(request.GetTaskId())
+	if id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "task_id обязателен")
+	}
+
+	paths := request.GetUpdateMask().GetPaths()
+	if len(paths) == 1 && paths[0] == "is_completed" {
+		var task *repo.Task
+		if request.GetIsCompleted() {
+			task, err = tm.service.CompleteTask(ctx, userID, id)
+		} else {
+			task, err = tm.service.ReopenTask(ctx, userID, id)
+		}
+		if err != nil {
+			return nil, tm.statusError(op, err)
+		}
+		return toResponse(task), nil
+	}
+
+	dto, err := updateDTOFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := tm.service.UpdateTask(ctx, userID, id, dto)
+	if err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return toResponse(task), nil
+}
+
+func (tm *gRPCServerApi) DeleteTask(ctx context.Context, request *tmv1.DeleteTaskRequest) (*emptypb.Empty, error) {
+	const op = "internal.tasks.grpc.DeleteTask"
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.GetTaskId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "task_id обязателен")
+	}
+
+	if err := tm.service.DeleteTask(ctx, userID, int(request.GetTaskId())); err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// updateDTOFromRequest Собирает частичное обновление по update_mask
+func updateDTOFromRequest(request *tmv1.UpdateTaskRequest) (usecases.UpdateTaskDTO, error) {
+	title := request.GetTitle()
+	description := request.GetDescription()
+	isCompleted := request.GetIsCompleted()
+	categoryID := int(request.GetTaskCategoryId())
+
+	paths := request.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		return usecases.UpdateTaskDTO{
+			Title:       &title,
+			Description: &description,
+			IsCompleted: &isCompleted,
+			CategoryID:  &categoryID,
+		}, nil
+	}
+
+	var dto usecases.UpdateTaskDTO
+	for _, path := range paths {
+		switch path {
+		case "title":
+			dto.Title = &title
+		case "description":
+			dto.Description = &description
+		case "is_completed":
+			dto.IsCompleted = &isCompleted
+		case "task_category_id":
+			dto.CategoryID = &categoryID
+		default:
+			return usecases.UpdateTaskDTO{}, status.Errorf(codes.InvalidArgument, "неизвестное поле в update_mask: %s", path)
+		}
+	}
+
+	return dto, nil
+}
+
+// userIDFromContext Возвращает владельца задач, проверенного интерцептором аутентификации
+func userIDFromContext(ctx context.Context) (int, error) {
+	userID, ok := authgrpc.UserIDFromContext(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "пользователь не аутентифицирован")
+	}
+
+	return userID, nil
+}
+
+// statusError Переводит ошибку сервиса задач в gRPC-статус
+func (tm *gRPCServerApi) statusError(op string, err error) error {
+	switch {
+	case errors.Is(err, repo.ErrTaskNotFound):
+		return status.Error(codes.NotFound, "задача не найдена")
+	case errors.Is(err, repo.ErrTaskForbidden):
+		return status.Error(codes.PermissionDenied, "нет доступа к задаче")
+	case errors.Is(err, repo.ErrCategoryNotFound):
+		return status.Error(codes.InvalidArgument, "категория задачи не найдена")
+	case errors.Is(err, usecases.ErrEmptyTitle):
+		return status.Error(codes.InvalidArgument, "название задачи не может быть пустым")
+	default:
+		tm.log.Error("Ошибка обработки задачи", slog.String("op", op), sl.Err(err))
+		return status.Error(codes.Internal, "внутренняя ошибка")
+	}
+}
+
+func toResponse(task *repo.Task) *tmv1.TaskResponse {
+	return &tmv1.TaskResponse{
+		TaskId:         int64(task.ID),
+		Title:          task.Title,
+		Description:    task.Description,
+		IsCompleted:    task.IsCompleted,
+		CreatedAt:      timestamppb.New(task.CreatedAt),
+		UpdatedAt:      timestamppb.New(task.UpdatedAt),
+		TaskCategoryId: int64(task.TaskCategory.ID),
+	}
+}
@@ -0,0 +1,44 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"task-manager/internal/tasks/usecases"
+)
+
+var errInvalidUserID = errors.New("некорректный идентификатор пользователя")
+
+// AdminListHandler эндпоинт просмотра администратором задач любого пользователя
+func AdminListHandler(log *slog.Logger, service *usecases.TaskService) http.HandlerFunc {
+	const op = "internal.handlers.rest.tasks.AdminListHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.Int("admin_id", userIDFromRequest(r)),
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || userID <= 0 {
+			log.Info("Некорректный идентификатор пользователя", slog.String("id", chi.URLParam(r, "id")))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: errInvalidUserID.Error()})
+			return
+		}
+
+		tasks, err := service.ListTasks(r.Context(), userID)
+		if err != nil {
+			renderServiceError(w, r, log, err)
+			return
+		}
+
+		log.Info("Администратор запросил задачи пользователя", slog.Int("user_id", userID))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, TasksResponse{Status: "ok", Tasks: tasks})
+	}
+}
@@ -0,0 +1,39 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/tasks/usecases"
+)
+
+// CompleteHandler эндпоинт отметки задачи выполненной
+func CompleteHandler(log *slog.Logger, service *usecases.TaskService) http.HandlerFunc {
+	const op = "internal.handlers.rest.tasks.CompleteHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.Int("user_id", userID),
+		)
+
+		id, err := taskIDFromRequest(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Некорректный идентификатор задачи"})
+			return
+		}
+
+		task, err := service.CompleteTask(r.Context(), userID, id)
+		if err != nil {
+			renderServiceError(w, r, log, err)
+			return
+		}
+
+		log.Info("Задача отмечена выполненной", slog.Int("task_id", id))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", Task: task})
+	}
+}
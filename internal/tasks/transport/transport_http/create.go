@@ -0,0 +1,54 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/tasks/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// CreateHandler эндпоинт создания задачи текущего пользователя
+func CreateHandler(log *slog.Logger, service *usecases.TaskService) http.HandlerFunc {
+	const op = "internal.handlers.rest.tasks.CreateHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.Int("user_id", userID),
+		)
+
+		var req CreateRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("Ошибка декодирования запроса", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Неверный формат запроса"})
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			log.Error("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Некорректные данные"})
+			return
+		}
+
+		task, err := service.CreateTask(r.Context(), userID, usecases.CreateTaskDTO{
+			Title:       req.Title,
+			Description: req.Description,
+			IsCompleted: req.IsCompleted,
+			CategoryID:  req.CategoryID,
+		})
+		if err != nil {
+			renderServiceError(w, r, log, err)
+			return
+		}
+
+		log.Info("Задача успешно создана", slog.Int("task_id", task.ID))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{Status: "ok", Task: task})
+	}
+}
@@ -0,0 +1,38 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/tasks/usecases"
+)
+
+// GetHandler эндпоинт получения задачи по идентификатору
+func GetHandler(log *slog.Logger, service *usecases.TaskService) http.HandlerFunc {
+	const op = "internal.handlers.rest.tasks.GetHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.Int("user_id", userID),
+		)
+
+		id, err := taskIDFromRequest(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Некорректный идентификатор задачи"})
+			return
+		}
+
+		task, err := service.GetTask(r.Context(), userID, id)
+		if err != nil {
+			renderServiceError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", Task: task})
+	}
+}
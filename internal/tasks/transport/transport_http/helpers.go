@@ -0,0 +1,58 @@
+package transport_http
+
+import (
+	"errors"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"task-manager/internal/tasks/repo"
+	"task-manager/internal/tasks/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+var errInvalidTaskID = errors.New("некорректный идентификатор задачи")
+
+// userIDFromRequest Достает идентификатор владельца из claim user_id токена
+func userIDFromRequest(r *http.Request) int {
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	userID, _ := claims["user_id"].(float64)
+	return int(userID)
+}
+
+// taskIDFromRequest Достает идентификатор задачи из пути запроса
+func taskIDFromRequest(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		return 0, errInvalidTaskID
+	}
+	return id, nil
+}
+
+// renderServiceError Переводит ошибку сервиса задач в HTTP-ответ
+func renderServiceError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, repo.ErrTaskNotFound):
+		log.Info("Задача не найдена")
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, Response{Status: "error", Error: "Задача не найдена"})
+	case errors.Is(err, repo.ErrTaskForbidden):
+		log.Info("Попытка доступа к чужой задаче")
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, Response{Status: "error", Error: "Нет доступа к задаче"})
+	case errors.Is(err, repo.ErrCategoryNotFound):
+		log.Info("Категория задачи не найдена")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Категория задачи не найдена"})
+	case errors.Is(err, usecases.ErrEmptyTitle):
+		log.Info("Пустое название задачи")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, Response{Status: "error", Error: "Название задачи не может быть пустым"})
+	default:
+		log.Error("Ошибка обработки задачи", sl.Err(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, Response{Status: "error", Error: "Что-то пошло не так"})
+	}
+}
@@ -0,0 +1,31 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/tasks/usecases"
+)
+
+// ListHandler эндпоинт получения всех задач текущего пользователя
+func ListHandler(log *slog.Logger, service *usecases.TaskService) http.HandlerFunc {
+	const op = "internal.handlers.rest.tasks.ListHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.Int("user_id", userID),
+		)
+
+		tasks, err := service.ListTasks(r.Context(), userID)
+		if err != nil {
+			renderServiceError(w, r, log, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, TasksResponse{Status: "ok", Tasks: tasks})
+	}
+}
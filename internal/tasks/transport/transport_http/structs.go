@@ -0,0 +1,29 @@
+package transport_http
+
+import "task-manager/internal/tasks/repo"
+
+type CreateRequest struct {
+	Title       string `json:"title" validate:"required"`
+	Description string `json:"description"`
+	IsCompleted bool   `json:"is_completed"`
+	CategoryID  int    `json:"category_id" validate:"gte=0"`
+}
+
+type UpdateRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	IsCompleted *bool   `json:"is_completed"`
+	CategoryID  *int    `json:"category_id" validate:"omitempty,gte=0"`
+}
+
+type Response struct {
+	Status string     `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	Task   *repo.Task `json:"task,omitempty"`
+}
+
+// TasksResponse Список задач. Поле tasks есть в ответе и при пустом списке
+type TasksResponse struct {
+	Status string      `json:"status"`
+	Tasks  []repo.Task `json:"tasks"`
+}
@@ -0,0 +1,17 @@
+package transport_http
+
+import (
+	"encoding/json"
+	"task-manager/internal/tasks/repo"
+	"testing"
+)
+
+func TestTasksResponseEmptyList(t *testing.T) {
+	body, err := json.Marshal(TasksResponse{Status: "ok", Tasks: []repo.Task{}})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if want := `{"status":"ok","tasks":[]}`; string(body) != want {
+		t.Errorf("ответ %s, ожидался %s", body, want)
+	}
+}
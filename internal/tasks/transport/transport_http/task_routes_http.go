@@ -0,0 +1,37 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/tasks/usecases"
+)
+
+func TasksRoutes(
+	r *chi.Mux,
+	log *slog.Logger,
+	taskService *usecases.TaskService,
+	authenticate func(http.Handler) http.Handler,
+	adminOnly func(http.Handler) http.Handler,
+) {
+	// Защищенные маршруты, владелец задачи берется из claim user_id
+	r.Group(func(r chi.Router) {
+		r.Use(authenticate) // Проверяет токен и его отзыв
+
+		r.Route("/tasks", func(r chi.Router) {
+			r.Post("/", CreateHandler(log, taskService))
+			r.Get("/", ListHandler(log, taskService))
+			r.Get("/{id}", GetHandler(log, taskService))
+			r.Patch("/{id}", UpdateHandler(log, taskService))
+			r.Delete("/{id}", DeleteHandler(log, taskService))
+			r.Post("/{id}/complete", CompleteHandler(log, taskService))
+		})
+	})
+
+	// Задачи любого пользователя для администратора
+	r.Group(func(r chi.Router) {
+		r.Use(adminOnly)
+
+		r.Get("/admin/users/{id}/tasks", AdminListHandler(log, taskService))
+	})
+}
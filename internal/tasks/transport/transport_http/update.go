@@ -0,0 +1,61 @@
+package transport_http
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"task-manager/internal/tasks/usecases"
+	"task-manager/pkg/logger/sl"
+)
+
+// UpdateHandler эндпоинт частичного обновления задачи
+func UpdateHandler(log *slog.Logger, service *usecases.TaskService) http.HandlerFunc {
+	const op = "internal.handlers.rest.tasks.UpdateHandler"
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := userIDFromRequest(r)
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.Int("user_id", userID),
+		)
+
+		id, err := taskIDFromRequest(r)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Некорректный идентификатор задачи"})
+			return
+		}
+
+		var req UpdateRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("Ошибка декодирования запроса", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Неверный формат запроса"})
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			log.Error("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, Response{Status: "error", Error: "Некорректные данные"})
+			return
+		}
+
+		task, err := service.UpdateTask(r.Context(), userID, id, usecases.UpdateTaskDTO{
+			Title:       req.Title,
+			Description: req.Description,
+			IsCompleted: req.IsCompleted,
+			CategoryID:  req.CategoryID,
+		})
+		if err != nil {
+			renderServiceError(w, r, log, err)
+			return
+		}
+
+		log.Info("Задача успешно обновлена", slog.Int("task_id", task.ID))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{Status: "ok", Task: task})
+	}
+}
@@ -0,0 +1,17 @@
+// go generate
+package usecases
+
+import (
+	"context"
+	"task-manager/pkg/events"
+)
+
+// Producer Отправка доменных событий, реализация в pkg/outbox
+type Producer interface {
+	Publish(ctx context.Context, event *events.Event) error
+}
+
+// Transactor Выполняет fn в одной транзакции, реализация в pkg/clients/posgresql
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
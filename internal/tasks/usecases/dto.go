@@ -6,3 +6,11 @@ type CreateTaskDTO struct {
 	IsCompleted bool   `json:"is_completed"`
 	CategoryID  int    `json:"category_id"`
 }
+
+// UpdateTaskDTO Частичное обновление задачи, nil-поля остаются без изменений
+type UpdateTaskDTO struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	IsCompleted *bool   `json:"is_completed"`
+	CategoryID  *int    `json:"category_id"`
+}
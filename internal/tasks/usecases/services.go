@@ -1 +1,282 @@
 package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"task-manager/internal/tasks/repo"
+	"task-manager/pkg/events"
+)
+
+var (
+	ErrEmptyTitle = errors.New("название задачи не может быть пустым")
+)
+
+type TaskService struct {
+	logger     *slog.Logger
+	repository repo.RepositoryInterface
+	producer   Producer
+	transactor Transactor
+}
+
+func NewTaskService(
+	logger *slog.Logger,
+	repository repo.RepositoryInterface,
+	producer Producer,
+	transactor Transactor,
+) *TaskService {
+	return &TaskService{logger: logger, repository: repository, producer: producer, transactor: transactor}
+}
+
+// CreateTask Создает задачу пользователя userID
+func (s *TaskService) CreateTask(ctx context.Context, userID int, dto CreateTaskDTO) (*repo.Task, error) {
+	const op = "internal.tasks.services.CreateTask"
+
+	title := strings.TrimSpace(dto.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+	}
+	if err := s.checkCategory(ctx, dto.CategoryID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	task := repo.Task{
+		Title:       title,
+		Description: dto.Description,
+		IsCompleted: dto.IsCompleted,
+		UserID:      userID,
+	}
+	task.TaskCategory.ID = dto.CategoryID
+
+	var created repo.Task
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.repository.Create(ctx, task)
+		if err != nil {
+			return err
+		}
+
+		created, err = s.repository.FindOne(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		return s.sendEvent(ctx, events.TypeTaskCreated, &created)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Задача создана", slog.Int("task_id", created.ID), slog.Int("user_id", userID))
+
+	return &created, nil
+}
+
+// GetTask Возвращает задачу, если она принадлежит пользователю userID
+func (s *TaskService) GetTask(ctx context.Context, userID int, id int) (*repo.Task, error) {
+	const op = "internal.tasks.services.GetTask"
+
+	task, err := s.repository.FindOne(ctx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &task, nil
+}
+
+// ListTasks Возвращает все задачи пользователя userID
+func (s *TaskService) ListTasks(ctx context.Context, userID int) ([]repo.Task, error) {
+	const op = "internal.tasks.services.ListTasks"
+
+	tasks, err := s.repository.FindAll(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tasks, nil
+}
+
+// UpdateTask Частично обновляет задачу пользователя userID. Смена is_completed
+// записывается событием выполнения или возобновления, как в CompleteTask и ReopenTask
+func (s *TaskService) UpdateTask(ctx context.Context, userID int, id int, dto UpdateTaskDTO) (*repo.Task, error) {
+	const op = "internal.tasks.services.UpdateTask"
+
+	var title string
+	if dto.Title != nil {
+		title = strings.TrimSpace(*dto.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+		}
+	}
+	if dto.CategoryID != nil {
+		if err := s.checkCategory(ctx, *dto.CategoryID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	updated, err := s.save(ctx, userID, id, func(task *repo.Task) string {
+		eventType := events.TypeTaskUpdated
+		if dto.Title != nil {
+			task.Title = title
+		}
+		if dto.Description != nil {
+			task.Description = *dto.Description
+		}
+		if dto.IsCompleted != nil && *dto.IsCompleted != task.IsCompleted {
+			task.IsCompleted = *dto.IsCompleted
+			eventType = completionEvent(task.IsCompleted)
+		}
+		if dto.CategoryID != nil {
+			task.TaskCategory.ID = *dto.CategoryID
+		}
+		return eventType
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updated, nil
+}
+
+// CompleteTask Отмечает задачу выполненной
+func (s *TaskService) CompleteTask(ctx context.Context, userID int, id int) (*repo.Task, error) {
+	const op = "internal.tasks.services.CompleteTask"
+
+	task, err := s.setCompleted(ctx, userID, id, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return task, nil
+}
+
+// ReopenTask Снимает с задачи отметку о выполнении
+func (s *TaskService) ReopenTask(ctx context.Context, userID int, id int) (*repo.Task, error) {
+	const op = "internal.tasks.services.ReopenTask"
+
+	task, err := s.setCompleted(ctx, userID, id, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return task, nil
+}
+
+// DeleteTask Удаляет задачу пользователя userID
+func (s *TaskService) DeleteTask(ctx context.Context, userID int, id int) error {
+	const op = "internal.tasks.services.DeleteTask"
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Delete(ctx, id, userID); err != nil {
+			return err
+		}
+
+		return s.publish(ctx, events.TypeTaskDeleted, userID, events.TaskDeleted{TaskID: id, UserID: userID})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Задача удалена", slog.Int("task_id", id), slog.Int("user_id", userID))
+
+	return nil
+}
+
+func (s *TaskService) setCompleted(ctx context.Context, userID int, id int, completed bool) (*repo.Task, error) {
+	return s.save(ctx, userID, id, func(task *repo.Task) string {
+		if task.IsCompleted == completed {
+			return ""
+		}
+		task.IsCompleted = completed
+		return completionEvent(completed)
+	})
+}
+
+// save Блокирует задачу в транзакции и применяет к ней change. change возвращает
+// тип события или пустую строку, если менять нечего. Измененная задача
+// перечитывается вместе с категорией, событие записывается в той же транзакции
+func (s *TaskService) save(ctx context.Context, userID int, id int, change func(task *repo.Task) string) (*repo.Task, error) {
+	var updated repo.Task
+	var eventType string
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		task, err := s.repository.FindOneForUpdate(ctx, id, userID)
+		if err != nil {
+			return err
+		}
+
+		eventType = change(&task)
+		if eventType == "" {
+			updated = task
+			return nil
+		}
+		if err := s.repository.Update(ctx, task); err != nil {
+			return err
+		}
+
+		updated, err = s.repository.FindOne(ctx, task.ID, task.UserID)
+		if err != nil {
+			return err
+		}
+
+		return s.sendEvent(ctx, eventType, &updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if eventType != "" {
+		s.logger.Info("Задача изменена",
+			slog.Int("task_id", updated.ID),
+			slog.Int("user_id", updated.UserID),
+			slog.String("event_type", eventType),
+		)
+	}
+	return &updated, nil
+}
+
+func completionEvent(completed bool) string {
+	if completed {
+		return events.TypeTaskCompleted
+	}
+	return events.TypeTaskReopened
+}
+
+// checkCategory Проверяет существование категории, нулевой идентификатор означает задачу без категории
+func (s *TaskService) checkCategory(ctx context.Context, categoryID int) error {
+	if categoryID == 0 {
+		return nil
+	}
+
+	exists, err := s.repository.CategoryExists(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repo.ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+// sendEvent Записывает событие с состоянием задачи после изменения
+func (s *TaskService) sendEvent(ctx context.Context, eventType string, task *repo.Task) error {
+	return s.publish(ctx, eventType, task.UserID, events.TaskChanged{
+		TaskID:      task.ID,
+		UserID:      task.UserID,
+		Title:       task.Title,
+		IsCompleted: task.IsCompleted,
+		CategoryID:  task.TaskCategory.ID,
+	})
+}
+
+// publish Записывает событие владельца задачи. Вызывается в транзакции
+// изменения, ошибка отменяет изменение
+func (s *TaskService) publish(ctx context.Context, eventType string, userID int, payload any) error {
+	event, err := events.New(eventType, events.UserActor(userID), userID, payload)
+	if err != nil {
+		return err
+	}
+
+	return s.producer.Publish(ctx, event)
+}
@@ -2,12 +2,39 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"log/slog"
 	"task-manager/pkg/clients/posgresql"
 )
 
+var (
+	ErrCategoryExists   = errors.New("категория с таким названием уже существует")
+	ErrCategoryNotFound = errors.New("категория задачи не найдена")
+)
+
+// wrapError — вспомогательная функция для обработки ошибок
+func wrapError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("%s: %w", op, ErrCategoryNotFound)
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case "23505": // Unique constraint violation
+			return fmt.Errorf("%s: %w", op, ErrCategoryExists)
+		default:
+			return fmt.Errorf("%s: %s: %w", op, pgErr.Code, err)
+		}
+	default:
+		return fmt.Errorf("%s: %w", op, err)
+	}
+}
+
 type RepositoryInterface interface {
-	Create(ctx context.Context, tc TaskCategory) (string, error)
+	Create(ctx context.Context, tc TaskCategory) (int, error)
 	FindAll(ctx context.Context) ([]TaskCategory, error)
 	FindOne(ctx context.Context, id int) (TaskCategory, error)
 	Update(ctx context.Context, tc TaskCategory) error
@@ -19,29 +46,103 @@ type repository struct {
 	logger   *slog.Logger
 }
 
-func (r *repository) Create(ctx context.Context, tc TaskCategory) (string, error) {
-	//TODO implement me
-	panic("implement me")
+func (r *repository) Create(ctx context.Context, tc TaskCategory) (int, error) {
+	const op = "tasks_categories.repo.Create"
+
+	stmt := `
+		INSERT INTO tasks_categories (title)
+		VALUES ($1)
+		RETURNING id
+		`
+	var id int
+	if err := r.dbClient.QueryRow(ctx, stmt, tc.Title).Scan(&id); err != nil {
+		return 0, wrapError(op, err)
+	}
+
+	return id, nil
 }
 
 func (r *repository) FindAll(ctx context.Context) ([]TaskCategory, error) {
-	//TODO implement me
-	panic("implement me")
+	const op = "tasks_categories.repo.FindAll"
+
+	stmt := `
+	SELECT id, title
+	FROM tasks_categories
+	ORDER BY id
+`
+	rows, err := r.dbClient.Query(ctx, stmt)
+	if err != nil {
+		return nil, wrapError(op, err)
+	}
+	defer rows.Close()
+
+	categories := make([]TaskCategory, 0)
+	for rows.Next() {
+		var tc TaskCategory
+		if err := rows.Scan(&tc.ID, &tc.Title); err != nil {
+			return nil, wrapError(op, err)
+		}
+		categories = append(categories, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapError(op, err)
+	}
+
+	return categories, nil
 }
 
 func (r *repository) FindOne(ctx context.Context, id int) (TaskCategory, error) {
-	//TODO implement me
-	panic("implement me")
+	const op = "tasks_categories.repo.FindOne"
+
+	stmt := `
+	SELECT id, title
+	FROM tasks_categories
+	WHERE id = $1
+`
+	var tc TaskCategory
+	if err := r.dbClient.QueryRow(ctx, stmt, id).Scan(&tc.ID, &tc.Title); err != nil {
+		return TaskCategory{}, wrapError(op, err)
+	}
+
+	return tc, nil
 }
 
 func (r *repository) Update(ctx context.Context, tc TaskCategory) error {
-	//TODO implement me
-	panic("implement me")
+	const op = "tasks_categories.repo.Update"
+
+	stmt := `
+	UPDATE tasks_categories
+	SET title = $1
+	WHERE id = $2
+`
+	pgTag, err := r.dbClient.Exec(ctx, stmt, tc.Title, tc.ID)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if pgTag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrCategoryNotFound)
+	}
+
+	return nil
 }
 
 func (r *repository) Delete(ctx context.Context, id int) error {
-	//TODO implement me
-	panic("implement me")
+	const op = "tasks_categories.repo.Delete"
+
+	query := `
+	DELETE
+	FROM tasks_categories
+	WHERE id = $1
+`
+	pgTag, err := r.dbClient.Exec(ctx, query, id)
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if pgTag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, ErrCategoryNotFound)
+	}
+
+	return nil
 }
 
 func NewRepository(dbClient posgresql.DBClient, logger *slog.Logger) RepositoryInterface {
@@ -2,37 +2,113 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"log/slog"
+	"slices"
+	"task-manager/internal/tasks_categories/repo"
+	"task-manager/internal/tasks_categories/usecases"
+	"task-manager/pkg/logger/sl"
 
 	tmv1 "github.com/dovgalb/taskmanager_proto/gen/go/task_manager"
 )
 
 type gRPCServerApi struct {
 	tmv1.UnimplementedTaskCategoryServer
+	log     *slog.Logger
+	service *usecases.CategoryService
 }
 
-func Register(gRPC *grpc.Server) {
-	tmv1.RegisterTaskCategoryServer(gRPC, &gRPCServerApi{})
+func Register(gRPC *grpc.Server, log *slog.Logger, service *usecases.CategoryService) {
+	tmv1.RegisterTaskCategoryServer(gRPC, &gRPCServerApi{log: log, service: service})
 }
 
 func (tm *gRPCServerApi) CreateTaskCategory(ctx context.Context, request *tmv1.CreateTaskCategoryRequest) (*tmv1.CreateTaskCategoryResponse, error) {
-	//TODO implement me
-	return &tmv1.CreateTaskCategoryResponse{TaskCategoryId: 1}, nil
+	const op = "internal.tasks_categories.grpc.CreateTaskCategory"
 
+	category, err := tm.service.CreateCategory(ctx, usecases.CreateTaskCategoryDTO{Title: request.GetTitle()})
+	if err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return &tmv1.CreateTaskCategoryResponse{TaskCategoryId: int64(category.ID)}, nil
 }
 
 func (tm *gRPCServerApi) ReadTaskCategory(ctx context.Context, request *tmv1.ReadTaskCategoryRequest) (*tmv1.TaskCategoryResponse, error) {
-	//TODO implement me
-	panic("implement me")
+	const op = "internal.tasks_categories.grpc.ReadTaskCategory"
+
+	if request.GetTaskCategoryId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "task_category_id обязателен")
+	}
+
+	category, err := tm.service.GetCategory(ctx, int(request.GetTaskCategoryId()))
+	if err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return toResponse(category), nil
 }
 
 func (tm *gRPCServerApi) UpdateTaskCategory(ctx context.Context, request *tmv1.UpdateTaskCategoryRequest) (*tmv1.TaskCategoryResponse, error) {
-	//TODO implement me
-	panic("implement me")
+	const op = "internal.tasks_categories.grpc.UpdateTaskCategory"
+
+	id := int(request.GetTaskCategoryId())
+	if id <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "task_category_id обязателен")
+	}
+
+	// title - единственное изменяемое поле, пустая маска означает полное обновление
+	if paths := request.GetUpdateMask().GetPaths(); len(paths) > 0 && !slices.Contains(paths, "title") {
+		category, err := tm.service.GetCategory(ctx, id)
+		if err != nil {
+			return nil, tm.statusError(op, err)
+		}
+		return toResponse(category), nil
+	}
+
+	category, err := tm.service.UpdateCategory(ctx, id, usecases.UpdateTaskCategoryDTO{Title: request.GetTitle()})
+	if err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return toResponse(category), nil
 }
 
 func (tm *gRPCServerApi) DeleteTaskCategory(ctx context.Context, request *tmv1.DeleteTaskCategoryRequest) (*emptypb.Empty, error) {
-	//TODO implement me
-	panic("implement me")
+	const op = "internal.tasks_categories.grpc.DeleteTaskCategory"
+
+	if request.GetTaskCategoryId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "task_category_id обязателен")
+	}
+
+	if err := tm.service.DeleteCategory(ctx, int(request.GetTaskCategoryId())); err != nil {
+		return nil, tm.statusError(op, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// statusError Переводит ошибку сервиса категорий в gRPC-статус
+func (tm *gRPCServerApi) statusError(op string, err error) error {
+	switch {
+	case errors.Is(err, repo.ErrCategoryNotFound):
+		return status.Error(codes.NotFound, "категория задачи не найдена")
+	case errors.Is(err, repo.ErrCategoryExists):
+		return status.Error(codes.AlreadyExists, "категория с таким названием уже существует")
+	case errors.Is(err, usecases.ErrEmptyTitle):
+		return status.Error(codes.InvalidArgument, "название категории не может быть пустым")
+	default:
+		tm.log.Error("Ошибка обработки категории задач", slog.String("op", op), sl.Err(err))
+		return status.Error(codes.Internal, "внутренняя ошибка")
+	}
+}
+
+func toResponse(category *repo.TaskCategory) *tmv1.TaskCategoryResponse {
+	return &tmv1.TaskCategoryResponse{
+		TaskCategoryId: int64(category.ID),
+		Title:          category.Title,
+	}
 }
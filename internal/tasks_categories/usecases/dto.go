@@ -3,3 +3,7 @@ package usecases
 type CreateTaskCategoryDTO struct {
 	Title string `json:"title"`
 }
+
+type UpdateTaskCategoryDTO struct {
+	Title string `json:"title"`
+}
@@ -1 +1,98 @@
 package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"task-manager/internal/tasks_categories/repo"
+)
+
+var (
+	ErrEmptyTitle = errors.New("название категории не может быть пустым")
+)
+
+type CategoryService struct {
+	logger     *slog.Logger
+	repository repo.RepositoryInterface
+}
+
+func NewCategoryService(logger *slog.Logger, repository repo.RepositoryInterface) *CategoryService {
+	return &CategoryService{logger: logger, repository: repository}
+}
+
+// CreateCategory Создает категорию задач с уникальным названием
+func (s *CategoryService) CreateCategory(ctx context.Context, dto CreateTaskCategoryDTO) (*repo.TaskCategory, error) {
+	const op = "internal.tasks_categories.services.CreateCategory"
+
+	title := strings.TrimSpace(dto.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+	}
+
+	category := repo.TaskCategory{Title: title}
+	id, err := s.repository.Create(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	category.ID = id
+
+	s.logger.Info("Категория задач создана", slog.String("op", op), slog.Int("category_id", id))
+
+	return &category, nil
+}
+
+// GetCategory Возвращает категорию задач по идентификатору
+func (s *CategoryService) GetCategory(ctx context.Context, id int) (*repo.TaskCategory, error) {
+	const op = "internal.tasks_categories.services.GetCategory"
+
+	category, err := s.repository.FindOne(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &category, nil
+}
+
+// ListCategories Возвращает все категории задач
+func (s *CategoryService) ListCategories(ctx context.Context) ([]repo.TaskCategory, error) {
+	const op = "internal.tasks_categories.services.ListCategories"
+
+	categories, err := s.repository.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return categories, nil
+}
+
+// UpdateCategory Переименовывает категорию задач
+func (s *CategoryService) UpdateCategory(ctx context.Context, id int, dto UpdateTaskCategoryDTO) (*repo.TaskCategory, error) {
+	const op = "internal.tasks_categories.services.UpdateCategory"
+
+	title := strings.TrimSpace(dto.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrEmptyTitle)
+	}
+
+	category := repo.TaskCategory{ID: id, Title: title}
+	if err := s.repository.Update(ctx, category); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &category, nil
+}
+
+// DeleteCategory Удаляет категорию, у задач этой категории category_id становится NULL
+func (s *CategoryService) DeleteCategory(ctx context.Context, id int) error {
+	const op = "internal.tasks_categories.services.DeleteCategory"
+
+	if err := s.repository.Delete(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.logger.Info("Категория задач удалена", slog.String("op", op), slog.Int("category_id", id))
+
+	return nil
+}
@@ -52,7 +52,7 @@ func main() {
 			wg.Add(1)
 			go sendRequest(&wg)
 		}
-		fmt.Println("цикл\n")
+		fmt.Println("цикл")
 	}
 	wg.Wait()
 
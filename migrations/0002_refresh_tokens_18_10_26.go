@@ -0,0 +1,26 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "refresh_tokens",
+		Up: `
+	CREATE TABLE refresh_tokens(
+	    id SERIAL PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	    family_id TEXT NOT NULL,
+	    token_hash TEXT NOT NULL UNIQUE,
+	    expires_at TIMESTAMPTZ NOT NULL,
+	    used_at TIMESTAMPTZ NULL,
+	    revoked_at TIMESTAMPTZ NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens(family_id);
+	CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens(user_id);
+`,
+		Down: `
+	DROP TABLE IF EXISTS refresh_tokens;
+`,
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "token_revocations",
+		Up: `
+	CREATE TABLE revoked_tokens(
+	    jti TEXT PRIMARY KEY,
+	    user_id INT NOT NULL,
+	    expires_at TIMESTAMPTZ NOT NULL,
+	    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens(expires_at);
+
+	-- Без внешнего ключа: отсечка должна пережить удаление пользователя
+	CREATE TABLE user_token_cutoffs(
+	    user_id INT PRIMARY KEY,
+	    revoked_before TIMESTAMPTZ NOT NULL
+	);
+`,
+		Down: `
+	DROP TABLE IF EXISTS user_token_cutoffs;
+	DROP TABLE IF EXISTS revoked_tokens;
+`,
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "password_reset",
+		Up: `
+	ALTER TABLE users ADD COLUMN email VARCHAR(255) NULL;
+	ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+	CREATE TABLE one_time_tokens(
+	    id SERIAL PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	    purpose VARCHAR(32) NOT NULL,
+	    token_hash TEXT NOT NULL UNIQUE,
+	    expires_at TIMESTAMPTZ NOT NULL,
+	    used_at TIMESTAMPTZ NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX one_time_tokens_user_id_purpose_idx ON one_time_tokens(user_id, purpose);
+`,
+		Down: `
+	DROP TABLE IF EXISTS one_time_tokens;
+	ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+	ALTER TABLE users DROP COLUMN IF EXISTS email;
+`,
+	})
+}
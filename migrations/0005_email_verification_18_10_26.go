@@ -0,0 +1,18 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "email_verification",
+		Up: `
+	ALTER TABLE users ADD COLUMN verified_at TIMESTAMPTZ NULL;
+
+	-- аккаунты, созданные до появления подтверждения, считаются подтвержденными
+	UPDATE users SET verified_at = created_at;
+`,
+		Down: `
+	DELETE FROM one_time_tokens WHERE purpose = 'email_verification';
+	ALTER TABLE users DROP COLUMN IF EXISTS verified_at;
+`,
+	})
+}
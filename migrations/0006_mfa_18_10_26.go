@@ -0,0 +1,31 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "mfa",
+		Up: `
+	CREATE TABLE user_mfa(
+	    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	    secret TEXT NOT NULL,
+	    enabled_at TIMESTAMPTZ NULL,
+	    last_used_step BIGINT NOT NULL DEFAULT 0,
+	    failed_attempts INT NOT NULL DEFAULT 0,
+	    locked_until TIMESTAMPTZ NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE mfa_recovery_codes(
+	    id SERIAL PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	    code_hash TEXT NOT NULL,
+	    used_at TIMESTAMPTZ NULL,
+	    UNIQUE (user_id, code_hash)
+	);
+`,
+		Down: `
+	DROP TABLE IF EXISTS mfa_recovery_codes;
+	DROP TABLE IF EXISTS user_mfa;
+`,
+	})
+}
@@ -0,0 +1,21 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "login_attempts",
+		Up: `
+	CREATE TABLE login_attempts(
+	    key TEXT PRIMARY KEY,
+	    failures INT NOT NULL DEFAULT 0,
+	    last_failure_at TIMESTAMPTZ NOT NULL,
+	    locked_until TIMESTAMPTZ NULL
+	);
+
+	CREATE INDEX login_attempts_last_failure_at_idx ON login_attempts(last_failure_at);
+`,
+		Down: `
+	DROP TABLE IF EXISTS login_attempts;
+`,
+	})
+}
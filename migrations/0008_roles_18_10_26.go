@@ -0,0 +1,22 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "roles",
+		Up: `
+	ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user'
+	    CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
+	ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ NULL;
+	ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+	CREATE INDEX users_role_idx ON users(role);
+`,
+		Down: `
+	DROP INDEX IF EXISTS users_role_idx;
+	ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
+	ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
+	ALTER TABLE users DROP COLUMN IF EXISTS role;
+`,
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "api_keys",
+		Up: `
+	CREATE TABLE api_keys(
+	    id SERIAL PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	    name VARCHAR(100) NOT NULL,
+	    prefix VARCHAR(16) NOT NULL,
+	    key_hash VARCHAR(64) NOT NULL UNIQUE,
+	    scopes TEXT[] NOT NULL DEFAULT '{}',
+	    expires_at TIMESTAMPTZ NULL,
+	    last_used_at TIMESTAMPTZ NULL,
+	    revoked_at TIMESTAMPTZ NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX api_keys_user_id_idx ON api_keys(user_id);
+`,
+		Down: `
+	DROP TABLE IF EXISTS api_keys;
+`,
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "oidc",
+		Up: `
+	CREATE TABLE user_identities(
+	    id SERIAL PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	    provider VARCHAR(50) NOT NULL,
+	    subject VARCHAR(255) NOT NULL,
+	    email VARCHAR(255) NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	    last_login_at TIMESTAMPTZ NULL,
+	    UNIQUE (provider, subject)
+	);
+
+	CREATE INDEX user_identities_user_id_idx ON user_identities(user_id);
+
+	CREATE TABLE oidc_login_states(
+	    state_hash VARCHAR(64) PRIMARY KEY,
+	    nonce VARCHAR(64) NOT NULL,
+	    code_verifier VARCHAR(128) NOT NULL,
+	    expires_at TIMESTAMPTZ NOT NULL
+	);
+`,
+		Down: `
+	DROP TABLE IF EXISTS oidc_login_states;
+	DROP TABLE IF EXISTS user_identities;
+`,
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "sessions",
+		Up: `
+	CREATE TABLE sessions(
+	    id VARCHAR(32) PRIMARY KEY,
+	    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	    ip VARCHAR(64) NOT NULL DEFAULT '',
+	    access_jti VARCHAR(32) NOT NULL,
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	    expires_at TIMESTAMPTZ NOT NULL,
+	    revoked_at TIMESTAMPTZ NULL
+	);
+
+	CREATE INDEX sessions_user_id_idx ON sessions(user_id);
+`,
+		Down: `
+	DROP TABLE IF EXISTS sessions;
+`,
+	})
+}
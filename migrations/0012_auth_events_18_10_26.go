@@ -0,0 +1,28 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "auth_events",
+		Up: `
+	CREATE TABLE auth_events(
+	    id BIGSERIAL PRIMARY KEY,
+	    type VARCHAR(32) NOT NULL,
+	    user_id INT NULL,
+	    login VARCHAR(255) NOT NULL DEFAULT '',
+	    ip VARCHAR(64) NOT NULL DEFAULT '',
+	    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	    request_id VARCHAR(128) NOT NULL DEFAULT '',
+	    details JSONB NOT NULL DEFAULT '{}',
+	    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX auth_events_user_id_idx ON auth_events(user_id, created_at DESC);
+	CREATE INDEX auth_events_type_idx ON auth_events(type, created_at DESC);
+	CREATE INDEX auth_events_created_at_idx ON auth_events(created_at DESC);
+`,
+		Down: `
+	DROP TABLE IF EXISTS auth_events;
+`,
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "outbox",
+		Up: `
+	CREATE SEQUENCE outbox_relay_seq;
+
+	CREATE TABLE outbox(
+	    id BIGSERIAL PRIMARY KEY,
+	    event_id VARCHAR(36) NOT NULL UNIQUE,
+	    event_type VARCHAR(64) NOT NULL,
+	    aggregate_key VARCHAR(64) NOT NULL DEFAULT '',
+	    event JSONB NOT NULL,
+	    attempts INT NOT NULL DEFAULT 0,
+	    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	    last_error TEXT NOT NULL DEFAULT '',
+	    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	    sent_at TIMESTAMP NULL,
+	    -- relay_seq порядок фиксации, проставляется Relay, когда событие становится видимым
+	    relay_seq BIGINT NULL UNIQUE
+	);
+
+	CREATE INDEX outbox_unsequenced_idx ON outbox(id) WHERE relay_seq IS NULL;
+	CREATE INDEX outbox_pending_idx ON outbox(relay_seq) WHERE sent_at IS NULL;
+	CREATE INDEX outbox_sent_at_idx ON outbox(sent_at) WHERE sent_at IS NOT NULL;
+`,
+		Down: `
+	DROP TABLE IF EXISTS outbox;
+	DROP SEQUENCE IF EXISTS outbox_relay_seq;
+`,
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Migration Шаг миграции схемы. Up применяет изменения, Down откатывает их
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+var registry = make(map[int64]Migration)
+
+// register Регистрирует шаг миграции, вызывается из init() файлов миграций
+func register(m Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic(fmt.Sprintf("миграция %d зарегистрирована дважды", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// All Возвращает все зарегистрированные миграции по возрастанию версии
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+
+	return all
+}
@@ -0,0 +1,207 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"task-manager/pkg/clients/posgresql"
+	"time"
+)
+
+// lockKey Ключ advisory-блокировки, не дающей двум экземплярам мигрировать одновременно
+const lockKey int64 = 6_03_2025
+
+var (
+	ErrUnknownVersion = errors.New("миграция с такой версией не зарегистрирована")
+)
+
+// Status Состояние одного шага миграции
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	log        *slog.Logger
+	dbClient   posgresql.DBClient
+	migrations []Migration
+}
+
+func NewMigrator(log *slog.Logger, dbClient posgresql.DBClient) *Migrator {
+	return &Migrator{log: log, dbClient: dbClient, migrations: All()}
+}
+
+// Up Применяет все непримененные миграции
+func (m *Migrator) Up(ctx context.Context) error {
+	const op = "migrations.Up"
+
+	return m.withLock(ctx, op, func(tx pgx.Tx, applied map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, tx, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down Откатывает steps последних примененных миграций
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	const op = "migrations.Down"
+
+	return m.withLock(ctx, op, func(tx pgx.Tx, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.rollback(ctx, tx, migration); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Goto Приводит схему к версии version: применяет недостающие миграции до нее
+// включительно и откатывает все более новые. Версия 0 откатывает все миграции
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	const op = "migrations.Goto"
+
+	if version != 0 {
+		if _, ok := registry[version]; !ok {
+			return fmt.Errorf("%s: %d: %w", op, version, ErrUnknownVersion)
+		}
+	}
+
+	return m.withLock(ctx, op, func(tx pgx.Tx, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; ok && migration.Version > version {
+				if err := m.rollback(ctx, tx, migration); err != nil {
+					return err
+				}
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+				if err := m.apply(ctx, tx, migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status Возвращает состояние всех зарегистрированных миграций
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	const op = "migrations.Status"
+
+	var statuses []Status
+	err := m.withLock(ctx, op, func(_ pgx.Tx, applied map[int64]time.Time) error {
+		statuses = make([]Status, 0, len(m.migrations))
+		for _, migration := range m.migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// withLock Выполняет fn в одной транзакции под advisory-блокировкой.
+// Ошибка любого шага откатывает всю операцию целиком
+func (m *Migrator) withLock(ctx context.Context, op string, fn func(tx pgx.Tx, applied map[int64]time.Time) error) error {
+	tx, err := m.dbClient.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("%s: блокировка: %w", op, err)
+	}
+
+	stmt := `
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+	    version BIGINT PRIMARY KEY,
+	    name TEXT NOT NULL,
+	    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+`
+	if _, err := tx.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	applied, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := fn(tx, applied); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, tx pgx.Tx, migration Migration) error {
+	if _, err := tx.Exec(ctx, migration.Up); err != nil {
+		return fmt.Errorf("применение миграции %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	if err != nil {
+		return fmt.Errorf("запись миграции %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	m.log.Info("Миграция применена", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	return nil
+}
+
+func (m *Migrator) rollback(ctx context.Context, tx pgx.Tx, migration Migration) error {
+	if _, err := tx.Exec(ctx, migration.Down); err != nil {
+		return fmt.Errorf("откат миграции %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+		return fmt.Errorf("удаление записи миграции %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	m.log.Info("Миграция откачена", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	return nil
+}
+
+func appliedVersions(ctx context.Context, tx pgx.Tx) (map[int64]time.Time, error) {
+	rows, err := tx.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,231 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"log/slog"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+// ErrSkipMessage Обработчик возвращает ошибку с ErrSkipMessage, если повтор не
+// поможет: сообщение подтверждается без обработки и не блокирует партицию
+var ErrSkipMessage = errors.New("сообщение пропущено обработчиком")
+
+// Handler Обработчик событий одного типа. Пока он возвращает ошибку, сообщение
+// не подтверждается и обрабатывается повторно
+type Handler interface {
+	Handle(ctx context.Context, event *events.Event) error
+}
+
+type HandlerFunc func(ctx context.Context, event *events.Event) error
+
+func (f HandlerFunc) Handle(ctx context.Context, event *events.Event) error {
+	return f(ctx, event)
+}
+
+// ConsumerPolicy Задержка между повторами обработки растет от RetryBackoffBase
+// вдвое, но не больше RetryBackoffMax
+type ConsumerPolicy struct {
+	RetryBackoffBase time.Duration
+	RetryBackoffMax  time.Duration
+}
+
+// Consumer Чтение событий группой потребителей. Смещение фиксируется только
+// после успешной обработки сообщения, поэтому после падения или ребаланса
+// необработанные сообщения читаются снова. Доставка "хотя бы один раз":
+// обработчики должны спокойно переносить повтор события с тем же id
+type Consumer struct {
+	logger   *slog.Logger
+	group    sarama.ConsumerGroup
+	topics   []string
+	handlers map[string]Handler
+	policy   ConsumerPolicy
+}
+
+func NewKafkaConsumer(
+	logger *slog.Logger,
+	brokers []string,
+	groupID string,
+	topics []string,
+	policy ConsumerPolicy,
+) (*Consumer, error) {
+	config := sarama.NewConfig()
+	config.Consumer.Return.Errors = true
+	// смещение фиксируется вручную после обработки
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	// sticky оставляет партиции за прежними владельцами, насколько это возможно
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+
+	group, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Consumer{
+		logger:   logger.With(slog.String("group", groupID)),
+		group:    group,
+		topics:   topics,
+		handlers: make(map[string]Handler),
+		policy:   policy,
+	}, nil
+}
+
+// Handle Регистрирует обработчик типа события. Вызывается до Run
+func (c *Consumer) Handle(eventType string, handler Handler) {
+	if _, exists := c.handlers[eventType]; exists {
+		panic("kafka: повторная регистрация обработчика " + eventType)
+	}
+	c.handlers[eventType] = handler
+}
+
+// Run Читает сообщения, пока не отменен ctx. После ребаланса группа
+// подключается заново с новым набором партиций
+func (c *Consumer) Run(ctx context.Context) error {
+	const op = "kafka.Consumer.Run"
+	log := c.logger.With(slog.String("op", op))
+
+	go func() {
+		for err := range c.group.Errors() {
+			log.Error("Ошибка группы потребителей", sl.Err(err))
+		}
+	}()
+
+	for {
+		err := c.group.Consume(ctx, c.topics, c)
+		if ctx.Err() != nil || errors.Is(err, sarama.ErrClosedConsumerGroup) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// Close Закрывает группу, фиксированные смещения сохраняются
+func (c *Consumer) Close() error {
+	return c.group.Close()
+}
+
+// Setup Вызывается после назначения партиций, до чтения
+func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
+	c.logger.Info("Назначены партиции",
+		slog.Any("claims", session.Claims()),
+		slog.Int("generation", int(session.GenerationID())),
+	)
+	return nil
+}
+
+// Cleanup Вызывается перед ребалансом или остановкой, когда все ConsumeClaim
+// завершились. Отмеченные смещения к этому моменту уже зафиксированы
+func (c *Consumer) Cleanup(session sarama.ConsumerGroupSession) error {
+	c.logger.Info("Партиции освобождены", slog.Int("generation", int(session.GenerationID())))
+	return nil
+}
+
+// ConsumeClaim Обрабатывает сообщения одной партиции по порядку. Сообщение
+// с ошибкой обработки повторяется, пока не будет обработано или пока сессия не
+// закончится: тогда его прочитает следующий владелец партиции
+func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if !c.processWithRetry(ctx, message) {
+				return nil
+			}
+
+			session.MarkMessage(message, "")
+			session.Commit()
+		}
+	}
+}
+
+// processWithRetry Возвращает false, если сессия закончилась раньше, чем
+// сообщение было обработано
+func (c *Consumer) processWithRetry(ctx context.Context, message *sarama.ConsumerMessage) bool {
+	log := c.logger.With(
+		slog.String("topic", message.Topic),
+		slog.Int("partition", int(message.Partition)),
+		slog.Int64("offset", message.Offset),
+	)
+
+	for attempt := 0; ; attempt++ {
+		err := c.process(ctx, message)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrSkipMessage) {
+			log.Warn("Сообщение пропущено", sl.Err(err))
+			return true
+		}
+
+		delay := c.backoff(attempt)
+		log.Error("Ошибка обработки сообщения, повтор позже",
+			slog.Int("attempt", attempt+1),
+			slog.Duration("retry_in", delay),
+			sl.Err(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// process Разбирает сообщение и передает событие обработчику его типа.
+// Сообщения, которые нельзя разобрать, и события без обработчика пропускаются:
+// повтор их не исправит
+func (c *Consumer) process(ctx context.Context, message *sarama.ConsumerMessage) error {
+	codec, err := events.CodecForContentType(header(message, HeaderContentType))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSkipMessage, err)
+	}
+	event, err := codec.Unmarshal(message.Value)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSkipMessage, err)
+	}
+	event.Key = string(message.Key)
+
+	handler, ok := c.handlers[event.Type]
+	if !ok {
+		c.logger.Debug("Нет обработчика события", slog.String("event_type", event.Type))
+		return nil
+	}
+
+	return handler.Handle(ctx, event)
+}
+
+func (c *Consumer) backoff(attempt int) time.Duration {
+	if attempt >= 30 {
+		return c.policy.RetryBackoffMax
+	}
+	delay := c.policy.RetryBackoffBase << attempt
+	if delay <= 0 || delay > c.policy.RetryBackoffMax {
+		return c.policy.RetryBackoffMax
+	}
+	return delay
+}
+
+func header(message *sarama.ConsumerMessage, key string) string {
+	for _, h := range message.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -1,58 +1,142 @@
 package kafka
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/IBM/sarama"
 	"log/slog"
+	"strconv"
+	"sync"
+	"task-manager/pkg/events"
 )
 
+// Заголовки сообщения с событием, чтобы потребитель мог выбрать обработчик
+// и кодек, не разбирая тело
+const (
+	HeaderContentType  = "content-type"
+	HeaderEventType    = "event-type"
+	HeaderEventVersion = "event-version"
+	HeaderEventID      = "event-id"
+)
+
+// ErrProducerUnavailable Нет соединения с брокером. Outbox.Relay в этом случае
+// откладывает событие и повторяет отправку позже
+var ErrProducerUnavailable = errors.New("нет соединения с Kafka")
+
+// Producer Отправка событий в Kafka. Если брокер недоступен при запуске,
+// соединение устанавливается при следующей отправке, а до тех пор Publish
+// возвращает ErrProducerUnavailable
 type Producer struct {
-	logger   *slog.Logger
+	logger  *slog.Logger
+	brokers []string
+	config  *sarama.Config
+	topic   string
+	codec   events.Codec
+
+	mu       sync.Mutex
 	producer sarama.SyncProducer
-	topic    string
 }
 
-func NewKafkaProducer(logger *slog.Logger, brokers []string, topic string) (*Producer, error) {
+// NewKafkaProducer Возвращает ошибку только при неверных настройках, недоступный
+// брокер запуску не мешает
+func NewKafkaProducer(logger *slog.Logger, brokers []string, topic string, codec events.Codec) (*Producer, error) {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 	config.Producer.Retry.Max = 5
+	// события одного ключа должны попадать в партицию в порядке отправки
+	config.Producer.Idempotent = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Net.MaxOpenRequests = 1
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Producer{
+		logger:  logger,
+		brokers: brokers,
+		config:  config,
+		topic:   topic,
+		codec:   codec,
+	}
+	if _, err := p.connect(); err != nil {
+		logger.Warn("Kafka недоступна, события будут отправлены после подключения", slog.Any("err", err))
+	}
+
+	return p, nil
+}
+
+// connect Возвращает соединение, при необходимости устанавливая его
+func (p *Producer) connect() (sarama.SyncProducer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.producer != nil {
+		return p.producer, nil
+	}
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
+	producer, err := sarama.NewSyncProducer(p.brokers, p.config)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrProducerUnavailable, err)
 	}
 
-	return &Producer{
-		logger:   logger,
-		producer: producer,
-		topic:    topic,
-	}, nil
+	p.producer = producer
+	return p.producer, nil
 }
 
-func (p *Producer) SendMessage(key, value string) error {
-	const op = "kafka.SendMessage"
+// Publish Отправляет событие с ключом партиции event.Key
+func (p *Producer) Publish(ctx context.Context, event *events.Event) error {
+	const op = "kafka.Publish"
 	log := p.logger.With(
 		slog.String("op", op),
-		slog.String("key", key),
+		slog.String("event_type", event.Type),
+		slog.String("event_id", event.ID),
 	)
 
+	value, err := p.codec.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	message := &sarama.ProducerMessage{
 		Topic: p.topic,
-		Key:   sarama.StringEncoder(key),
-		Value: sarama.StringEncoder(value),
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte(HeaderContentType), Value: []byte(p.codec.ContentType())},
+			{Key: []byte(HeaderEventType), Value: []byte(event.Type)},
+			{Key: []byte(HeaderEventVersion), Value: []byte(strconv.Itoa(event.Version))},
+			{Key: []byte(HeaderEventID), Value: []byte(event.ID)},
+		},
+	}
+	if event.Key != "" {
+		message.Key = sarama.StringEncoder(event.Key)
 	}
 
-	partition, offset, err := p.producer.SendMessage(message)
+	producer, err := p.connect()
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info(fmt.Sprintf("Сообщение отправлено: partition=%d, offset=%d, key=%s, value=%s", partition, offset, key, value))
-	return nil
+	partition, offset, err := producer.SendMessage(message)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
+	log.Info("Событие отправлено",
+		slog.Int("partition", int(partition)),
+		slog.Int64("offset", offset),
+		slog.String("key", event.Key),
+	)
+	return nil
 }
 
-// Close закрывает продюсер.
+// Close закрывает продюсер, если соединение было установлено
 func (p *Producer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.producer == nil {
+		return nil
+	}
 	return p.producer.Close()
 }
@@ -0,0 +1,41 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"task-manager/pkg/events"
+	"testing"
+)
+
+// unreachableBroker Адрес, на котором гарантированно никто не слушает
+func unreachableBroker(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	return addr
+}
+
+func TestProducerWithoutBroker(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	producer, err := NewKafkaProducer(log, []string{unreachableBroker(t)}, "events", events.JSONCodec{})
+	if err != nil {
+		t.Fatalf("NewKafkaProducer: %v", err)
+	}
+
+	err = producer.Publish(context.Background(), &events.Event{ID: "1", Type: "user.registered", Version: 1})
+	if !errors.Is(err, ErrProducerUnavailable) {
+		t.Errorf("Publish ошибка = %v, ожидалась %v", err, ErrProducerUnavailable)
+	}
+	if err := producer.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
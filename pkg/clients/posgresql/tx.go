@@ -0,0 +1,54 @@
+package posgresql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type txKey struct{}
+
+// Transactor Выполняет несколько записей в одной транзакции. Транзакция
+// передается через контекст: репозитории, берущие соединение через Conn,
+// пишут в нее без изменения своих сигнатур
+type Transactor struct {
+	db DBClient
+}
+
+func NewTransactor(db DBClient) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTx Выполняет fn в транзакции и фиксирует ее, если fn не вернула ошибку.
+// Вложенный вызов выполняется в уже открытой транзакции
+func (t *Transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "pkg.clients.posgresql.WithinTx"
+
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Conn Транзакция из контекста, если она открыта через WithinTx, иначе db
+func Conn(ctx context.Context, db DBClient) DBClient {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
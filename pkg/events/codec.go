@@ -0,0 +1,221 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Форматы сериализации и соответствующие им content-type заголовка сообщения
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+var (
+	ErrUnknownFormat = errors.New("неизвестный формат событий")
+	ErrMalformed     = errors.New("событие не удалось разобрать")
+)
+
+// Codec Сериализация конверта события
+type Codec interface {
+	ContentType() string
+	Marshal(e *Event) ([]byte, error)
+	Unmarshal(data []byte) (*Event, error)
+}
+
+// NewCodec Кодек по имени формата из конфигурации
+func NewCodec(format string) (Codec, error) {
+	switch format {
+	case FormatJSON, "":
+		return JSONCodec{}, nil
+	case FormatProtobuf:
+		return ProtobufCodec{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// CodecForContentType Кодек для разбора сообщения по его content-type.
+// Сообщения без заголовка считаются JSON
+func CodecForContentType(contentType string) (Codec, error) {
+	switch contentType {
+	case ContentTypeJSON, "":
+		return JSONCodec{}, nil
+	case ContentTypeProtobuf:
+		return ProtobufCodec{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, contentType)
+	}
+}
+
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (JSONCodec) Marshal(e *Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (JSONCodec) Unmarshal(data []byte) (*Event, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return &e, nil
+}
+
+// ProtobufCodec Кодирует конверт по схеме events.proto. Данные события
+// передаются как google.protobuf.Struct, поэтому сгенерированный код не нужен
+type ProtobufCodec struct{}
+
+// Номера полей из events.proto
+const (
+	fieldEventID         protowire.Number = 1
+	fieldEventType       protowire.Number = 2
+	fieldEventVersion    protowire.Number = 3
+	fieldEventOccurredAt protowire.Number = 4
+	fieldEventActor      protowire.Number = 5
+	fieldEventPayload    protowire.Number = 6
+
+	fieldActorType   protowire.Number = 1
+	fieldActorUserID protowire.Number = 2
+)
+
+func (ProtobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (ProtobufCodec) Marshal(e *Event) ([]byte, error) {
+	occurredAt, err := proto.Marshal(timestamppb.New(e.OccurredAt))
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(e.Payload, &fields); err != nil {
+		return nil, err
+	}
+	payload, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var actor []byte
+	actor = protowire.AppendTag(actor, fieldActorType, protowire.BytesType)
+	actor = protowire.AppendString(actor, e.Actor.Type)
+	if e.Actor.UserID != 0 {
+		actor = protowire.AppendTag(actor, fieldActorUserID, protowire.VarintType)
+		actor = protowire.AppendVarint(actor, uint64(e.Actor.UserID))
+	}
+
+	var b []byte
+	b = protowire.AppendTag(b, fieldEventID, protowire.BytesType)
+	b = protowire.AppendString(b, e.ID)
+	b = protowire.AppendTag(b, fieldEventType, protowire.BytesType)
+	b = protowire.AppendString(b, e.Type)
+	b = protowire.AppendTag(b, fieldEventVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(e.Version))
+	b = protowire.AppendTag(b, fieldEventOccurredAt, protowire.BytesType)
+	b = protowire.AppendBytes(b, occurredAt)
+	b = protowire.AppendTag(b, fieldEventActor, protowire.BytesType)
+	b = protowire.AppendBytes(b, actor)
+	b = protowire.AppendTag(b, fieldEventPayload, protowire.BytesType)
+	b = protowire.AppendBytes(b, payloadBytes)
+
+	return b, nil
+}
+
+func (ProtobufCodec) Unmarshal(data []byte) (*Event, error) {
+	var e Event
+	err := walkFields(data, func(num protowire.Number, value []byte, varint uint64) error {
+		switch num {
+		case fieldEventID:
+			e.ID = string(value)
+		case fieldEventType:
+			e.Type = string(value)
+		case fieldEventVersion:
+			e.Version = int(varint)
+		case fieldEventOccurredAt:
+			var ts timestamppb.Timestamp
+			if err := proto.Unmarshal(value, &ts); err != nil {
+				return err
+			}
+			e.OccurredAt = ts.AsTime()
+		case fieldEventActor:
+			return walkFields(value, func(num protowire.Number, value []byte, varint uint64) error {
+				switch num {
+				case fieldActorType:
+					e.Actor.Type = string(value)
+				case fieldActorUserID:
+					e.Actor.UserID = int(varint)
+				}
+				return nil
+			})
+		case fieldEventPayload:
+			var payload structpb.Struct
+			if err := proto.Unmarshal(value, &payload); err != nil {
+				return err
+			}
+			data, err := json.Marshal(payload.AsMap())
+			if err != nil {
+				return err
+			}
+			e.Payload = data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	return &e, nil
+}
+
+// walkFields Перебирает поля сообщения protobuf. Для varint-полей значение
+// передается в varint, для строк и вложенных сообщений - в value. Неизвестные
+// поля пропускаются, чтобы старые потребители читали новые версии конверта
+func walkFields(data []byte, fn func(num protowire.Number, value []byte, varint uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var (
+			value  []byte
+			varint uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := fn(num, value, varint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
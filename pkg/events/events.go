@@ -0,0 +1,124 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrUnknownType     = errors.New("неизвестный тип события")
+	ErrPayloadMismatch = errors.New("данные не соответствуют типу события")
+)
+
+// Типы инициаторов события
+const (
+	ActorUser   = "user"
+	ActorAdmin  = "admin"
+	ActorSystem = "system"
+)
+
+// Actor Кто вызвал событие. UserID пуст для системных событий
+type Actor struct {
+	Type   string `json:"type"`
+	UserID int    `json:"user_id,omitempty"`
+}
+
+func UserActor(userID int) Actor {
+	return Actor{Type: ActorUser, UserID: userID}
+}
+
+func AdminActor(adminID int) Actor {
+	return Actor{Type: ActorAdmin, UserID: adminID}
+}
+
+func SystemActor() Actor {
+	return Actor{Type: ActorSystem}
+}
+
+// Event Конверт доменного события. Payload - JSON с данными типа из реестра,
+// его схема меняется только вместе с Version
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      Actor           `json:"actor"`
+	Payload    json.RawMessage `json:"payload"`
+	// Key ключ партиции: id пользователя, к которому относится событие, чтобы
+	// события одного пользователя читались в порядке записи. В конверт не входит
+	Key string `json:"-"`
+}
+
+// New Создает событие зарегистрированного типа. subjectUserID - пользователь,
+// к которому относится событие, по нему выбирается партиция
+func New(eventType string, actor Actor, subjectUserID int, payload any) (*Event, error) {
+	const op = "pkg.events.New"
+
+	definition, ok := Lookup(eventType)
+	if !ok {
+		return nil, fmt.Errorf("%s: %s: %w", op, eventType, ErrUnknownType)
+	}
+	want := reflect.TypeOf(definition.New())
+	if got := reflect.TypeOf(payload); got != want && got != want.Elem() {
+		return nil, fmt.Errorf("%s: %s ждет %s: %w", op, eventType, want.Elem(), ErrPayloadMismatch)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	event := &Event{
+		ID:         id,
+		Type:       eventType,
+		Version:    definition.Version,
+		OccurredAt: time.Now().UTC(),
+		Actor:      actor,
+		Payload:    data,
+	}
+	if subjectUserID > 0 {
+		event.Key = strconv.Itoa(subjectUserID)
+	}
+
+	return event, nil
+}
+
+// DecodePayload Разбирает данные события в v
+func (e *Event) DecodePayload(v any) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// DecodeRegistered Разбирает данные в структуру из реестра, например *UserRegistered
+func (e *Event) DecodeRegistered() (any, error) {
+	definition, ok := Lookup(e.Type)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", e.Type, ErrUnknownType)
+	}
+
+	payload := definition.New()
+	if err := e.DecodePayload(payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// newID Случайный UUID версии 4
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"slices"
+	"time"
+)
+
+// Типы событий. Имя и версия - контракт с потребителями: несовместимое изменение
+// данных требует новой версии, переименование - нового типа
+const (
+	TypeUserRegistered          = "user.registered"
+	TypeUserLoggedIn            = "user.logged_in"
+	TypeUserLoginLocked         = "user.login_locked"
+	TypeUserUpdated             = "user.updated"
+	TypeUserPasswordChanged     = "user.password_changed"
+	TypeUserDeleted             = "user.deleted"
+	TypeUserEmailVerified       = "user.email_verified"
+	TypeUserMFAEnabled          = "user.mfa_enabled"
+	TypeUserMFADisabled         = "user.mfa_disabled"
+	TypeUserDisabled            = "user.disabled"
+	TypeUserEnabled             = "user.enabled"
+	TypeUserPasswordResetForced = "user.password_reset_forced"
+	TypeUserRoleChanged         = "user.role_changed"
+	TypeUserAPIKeyCreated       = "user.api_key_created"
+
+	TypeTaskCreated   = "task.created"
+	TypeTaskUpdated   = "task.updated"
+	TypeTaskCompleted = "task.completed"
+	TypeTaskReopened  = "task.reopened"
+	TypeTaskDeleted   = "task.deleted"
+)
+
+// Definition Описание типа события: текущая версия и структура данных
+type Definition struct {
+	Type    string
+	Version int
+	// New Возвращает указатель на пустую структуру данных события
+	New func() any
+}
+
+var registry = map[string]Definition{}
+
+func init() {
+	register(TypeUserRegistered, 1, func() any { return &UserRegistered{} })
+	register(TypeUserLoggedIn, 1, func() any { return &UserLoggedIn{} })
+	register(TypeUserLoginLocked, 1, func() any { return &UserLoginLocked{} })
+	register(TypeUserUpdated, 1, func() any { return &UserUpdated{} })
+	register(TypeUserPasswordChanged, 1, func() any { return &UserPasswordChanged{} })
+	register(TypeUserDeleted, 1, func() any { return &UserRef{} })
+	register(TypeUserEmailVerified, 1, func() any { return &UserEmailVerified{} })
+	register(TypeUserMFAEnabled, 1, func() any { return &UserRef{} })
+	register(TypeUserMFADisabled, 1, func() any { return &UserRef{} })
+	register(TypeUserDisabled, 1, func() any { return &UserRef{} })
+	register(TypeUserEnabled, 1, func() any { return &UserRef{} })
+	register(TypeUserPasswordResetForced, 1, func() any { return &UserRef{} })
+	register(TypeUserRoleChanged, 1, func() any { return &UserRoleChanged{} })
+	register(TypeUserAPIKeyCreated, 1, func() any { return &UserAPIKeyCreated{} })
+
+	register(TypeTaskCreated, 1, func() any { return &TaskChanged{} })
+	register(TypeTaskUpdated, 1, func() any { return &TaskChanged{} })
+	register(TypeTaskCompleted, 1, func() any { return &TaskChanged{} })
+	register(TypeTaskReopened, 1, func() any { return &TaskChanged{} })
+	register(TypeTaskDeleted, 1, func() any { return &TaskDeleted{} })
+}
+
+func register(eventType string, version int, newPayload func() any) {
+	if _, exists := registry[eventType]; exists {
+		panic("events: повторная регистрация типа " + eventType)
+	}
+	registry[eventType] = Definition{Type: eventType, Version: version, New: newPayload}
+}
+
+// Lookup Описание зарегистрированного типа события
+func Lookup(eventType string) (Definition, bool) {
+	definition, ok := registry[eventType]
+	return definition, ok
+}
+
+// Types Все зарегистрированные типы событий по алфавиту
+func Types() []string {
+	types := make([]string, 0, len(registry))
+	for eventType := range registry {
+		types = append(types, eventType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// UserRef Событие, для которого достаточно знать пользователя
+type UserRef struct {
+	UserID int    `json:"user_id"`
+	Login  string `json:"login,omitempty"`
+}
+
+type UserRegistered struct {
+	UserID int    `json:"user_id"`
+	Login  string `json:"login"`
+	Email  string `json:"email,omitempty"`
+}
+
+// UserLoggedIn Method - password или oidc, Provider заполнен для oidc
+type UserLoggedIn struct {
+	UserID   int    `json:"user_id"`
+	Login    string `json:"login"`
+	Method   string `json:"method"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// UserLoginLocked Key - заблокированный счетчик: login:<логин> или ip:<адрес>
+type UserLoginLocked struct {
+	Login    string    `json:"login"`
+	Key      string    `json:"key"`
+	Failures int       `json:"failures"`
+	Until    time.Time `json:"until"`
+}
+
+type UserUpdated struct {
+	UserID       int    `json:"user_id"`
+	OldLogin     string `json:"old_login"`
+	Login        string `json:"login"`
+	EmailChanged bool   `json:"email_changed"`
+}
+
+// UserPasswordChanged Method - change при смене пароля, reset при сбросе по ссылке
+type UserPasswordChanged struct {
+	UserID int    `json:"user_id"`
+	Login  string `json:"login"`
+	Method string `json:"method"`
+}
+
+type UserEmailVerified struct {
+	UserID int    `json:"user_id"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+}
+
+type UserRoleChanged struct {
+	UserID int    `json:"user_id"`
+	Login  string `json:"login"`
+	Role   string `json:"role"`
+}
+
+// UserAPIKeyCreated Prefix - открытое начало ключа, сам ключ в событие не попадает
+type UserAPIKeyCreated struct {
+	UserID int      `json:"user_id"`
+	KeyID  int      `json:"key_id"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+}
+
+// TaskChanged Состояние задачи после изменения. CategoryID 0 - задача без категории
+type TaskChanged struct {
+	TaskID      int    `json:"task_id"`
+	UserID      int    `json:"user_id"`
+	Title       string `json:"title"`
+	IsCompleted bool   `json:"is_completed"`
+	CategoryID  int    `json:"category_id,omitempty"`
+}
+
+type TaskDeleted struct {
+	TaskID int `json:"task_id"`
+	UserID int `json:"user_id"`
+}
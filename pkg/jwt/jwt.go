@@ -1,23 +1,207 @@
 package jwt
 
 import (
-	"github.com/golang-jwt/jwt/v5"
-	"task-manager/internal/auth/repo"
+	"errors"
+	"fmt"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-chi/render"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	jwxjwt "github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+	"task-manager/internal/config"
 	"time"
 )
 
-func NewToken(user repo.User, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+var (
+	ErrUnsupportedAlgorithm = errors.New("неподдерживаемый алгоритм подписи")
+	ErrSigningKeyNotFound   = errors.New("ключ подписи не найден среди ключей")
+)
+
+// Issuer Единственное место, где выпускаются и проверяются токены сервиса.
+// Новые токены подписываются текущим ключом, проверка принимает любой из
+// действующих ключей по kid, что позволяет ротировать ключи без разлогина
+type Issuer struct {
+	alg        jwa.SignatureAlgorithm
+	issuer     string
+	signKey    jwk.Key
+	verifyKeys jwk.Set
+	publicKeys jwk.Set
+}
+
+func NewIssuer(cnf config.JWT) (*Issuer, error) {
+	const op = "pkg.jwt.NewIssuer"
+
+	alg := jwa.SignatureAlgorithm(cnf.Algorithm)
+	switch alg {
+	case jwa.HS256, jwa.RS256, jwa.EdDSA:
+	default:
+		return nil, fmt.Errorf("%s: %s: %w", op, cnf.Algorithm, ErrUnsupportedAlgorithm)
+	}
+
+	issuer := &Issuer{
+		alg:        alg,
+		issuer:     cnf.Issuer,
+		verifyKeys: jwk.NewSet(),
+		publicKeys: jwk.NewSet(),
+	}
+
+	for _, keyCnf := range cnf.Keys {
+		key, err := parseKey(alg, keyCnf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ключ %s: %w", op, keyCnf.ID, err)
+		}
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["id"] = user.ID
-	claims["login"] = user.Login
-	claims["exp"] = time.Now().Add(duration).Unix()
+		if keyCnf.ID == cnf.SigningKeyID {
+			issuer.signKey = key
+		}
 
-	tokenString, err := token.SignedString([]byte("secret"))
+		if alg == jwa.HS256 {
+			if err := issuer.verifyKeys.AddKey(key); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		public, err := key.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%s: ключ %s: %w", op, keyCnf.ID, err)
+		}
+		if err := issuer.verifyKeys.AddKey(public); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := issuer.publicKeys.AddKey(public); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if issuer.signKey == nil {
+		return nil, fmt.Errorf("%s: %s: %w", op, cnf.SigningKeyID, ErrSigningKeyNotFound)
+	}
+	if alg != jwa.HS256 {
+		if isPrivate, err := jwk.IsPrivateKey(issuer.signKey); err != nil || !isPrivate {
+			return nil, fmt.Errorf("%s: для ключа подписи %s нужен приватный ключ", op, cnf.SigningKeyID)
+		}
+	}
+
+	return issuer, nil
+}
+
+// Issue Подписывает claims текущим ключом, проставляя iss, iat и exp через ttl
+func (i *Issuer) Issue(claims map[string]interface{}, ttl time.Duration) (jwxjwt.Token, string, error) {
+	return i.IssueAt(claims, time.Now(), ttl)
+}
+
+// IssueAt Как Issue, но с заданным временем выпуска. Нужен, чтобы токен,
+// выпущенный сразу после отзыва всех токенов, не попал под отсечку
+func (i *Issuer) IssueAt(claims map[string]interface{}, now time.Time, ttl time.Duration) (jwxjwt.Token, string, error) {
+	token := jwxjwt.New()
+	for k, v := range claims {
+		if err := token.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := token.Set(jwxjwt.IssuedAtKey, now); err != nil {
+		return nil, "", err
+	}
+	if err := token.Set(jwxjwt.ExpirationKey, now.Add(ttl)); err != nil {
+		return nil, "", err
+	}
+	if i.issuer != "" {
+		if err := token.Set(jwxjwt.IssuerKey, i.issuer); err != nil {
+			return nil, "", err
+		}
+	}
+
+	// kid попадает в заголовок из ключа
+	signed, err := jwxjwt.Sign(token, jwxjwt.WithKey(i.alg, i.signKey))
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+
+	return token, string(signed), nil
+}
+
+// Parse Проверяет подпись по kid из заголовка, срок действия и издателя
+func (i *Issuer) Parse(tokenString string) (jwxjwt.Token, error) {
+	options := []jwxjwt.ParseOption{
+		jwxjwt.WithKeySet(i.verifyKeys),
+		jwxjwt.WithValidate(true),
+		jwxjwt.WithAcceptableSkew(5 * time.Second),
+	}
+	if i.issuer != "" {
+		options = append(options, jwxjwt.WithIssuer(i.issuer))
+	}
+
+	token, err := jwxjwt.Parse([]byte(tokenString), options...)
+	if err != nil {
+		return nil, jwtauth.ErrorReason(err)
+	}
+
+	return token, nil
+}
+
+// Verifier http middleware, аналог jwtauth.Verifier с проверкой по набору ключей.
+// Результат кладется в контекст jwtauth, поэтому jwtauth.Authenticator и
+// jwtauth.FromContext продолжают работать
+func (i *Issuer) Verifier() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := jwtauth.TokenFromHeader(r)
+			if tokenString == "" {
+				tokenString = jwtauth.TokenFromCookie(r)
+			}
+
+			var token jwxjwt.Token
+			err := jwtauth.ErrNoTokenFound
+			if tokenString != "" {
+				token, err = i.Parse(tokenString)
+			}
+
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// JWKSHandler Публикует публичные ключи для офлайн-проверки токенов другими сервисами.
+// В режиме HS256 набор пуст: симметричный секрет не публикуется
+func (i *Issuer) JWKSHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		render.JSON(w, r, i.publicKeys)
+	}
+}
+
+func parseKey(alg jwa.SignatureAlgorithm, cnf config.JWTKey) (jwk.Key, error) {
+	var key jwk.Key
+	var err error
+
+	if alg == jwa.HS256 {
+		key, err = jwk.FromRaw(cnf.Material)
+	} else {
+		key, err = jwk.ParseKey(cnf.Material, jwk.WithPEM(true))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expected := map[jwa.SignatureAlgorithm]jwa.KeyType{
+		jwa.HS256: jwa.OctetSeq,
+		jwa.RS256: jwa.RSA,
+		jwa.EdDSA: jwa.OKP,
+	}[alg]
+	if key.KeyType() != expected {
+		return nil, fmt.Errorf("тип ключа %s не подходит для %s: %w", key.KeyType(), alg, ErrUnsupportedAlgorithm)
+	}
+
+	if err := key.Set(jwk.KeyIDKey, cnf.ID); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return nil, err
 	}
 
-	return tokenString, nil
+	return key, nil
 }
@@ -0,0 +1,446 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"task-manager/internal/config"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	jwxjwt "github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const testIssuer = "task-manager-test"
+
+// testKey Сгенерированная пара ключей в PEM, как ее читает loadJWT
+type testKey struct {
+	private []byte
+	public  []byte
+}
+
+func pemKeys(t *testing.T, private, public any) testKey {
+	t.Helper()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	return testKey{
+		private: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+		public:  pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}),
+	}
+}
+
+func rsaKey(t *testing.T) testKey {
+	t.Helper()
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	return pemKeys(t, private, &private.PublicKey)
+}
+
+func ed25519Key(t *testing.T) testKey {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	return pemKeys(t, private, public)
+}
+
+func hmacKey(t *testing.T) testKey {
+	t.Helper()
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return testKey{private: secret}
+}
+
+func newTestIssuer(t *testing.T, alg, signingKeyID string, keys ...config.JWTKey) *Issuer {
+	t.Helper()
+
+	issuer, err := NewIssuer(config.JWT{
+		Algorithm:    alg,
+		SigningKeyID: signingKeyID,
+		Keys:         keys,
+		Issuer:       testIssuer,
+	})
+	if err != nil {
+		t.Fatalf("NewIssuer: %v", err)
+	}
+	return issuer
+}
+
+func headerKeyID(t *testing.T, signed string) string {
+	t.Helper()
+
+	msg, err := jws.Parse([]byte(signed))
+	if err != nil {
+		t.Fatalf("jws.Parse: %v", err)
+	}
+	return msg.Signatures()[0].ProtectedHeaders().KeyID()
+}
+
+func TestIssuerRoundTrip(t *testing.T) {
+	tests := []struct {
+		alg string
+		key func(t *testing.T) testKey
+	}{
+		{alg: "HS256", key: hmacKey},
+		{alg: "RS256", key: rsaKey},
+		{alg: "EdDSA", key: ed25519Key},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			issuer := newTestIssuer(t, tt.alg, "k1", config.JWTKey{ID: "k1", Material: tt.key(t).private})
+
+			_, signed, err := issuer.Issue(map[string]interface{}{"sub": "42", "role": "admin"}, time.Minute)
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+			if kid := headerKeyID(t, signed); kid != "k1" {
+				t.Errorf("kid = %q, ожидался k1", kid)
+			}
+
+			token, err := issuer.Parse(signed)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if token.Subject() != "42" || token.Issuer() != testIssuer {
+				t.Errorf("sub = %q, iss = %q", token.Subject(), token.Issuer())
+			}
+			if role, _ := token.Get("role"); role != "admin" {
+				t.Errorf("role = %v", role)
+			}
+		})
+	}
+}
+
+func TestIssuerKeyRotation(t *testing.T) {
+	k1, k2 := rsaKey(t), rsaKey(t)
+
+	before := newTestIssuer(t, "RS256", "k1", config.JWTKey{ID: "k1", Material: k1.private})
+	_, oldToken, err := before.Issue(map[string]interface{}{"sub": "1"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// новый ключ подписи, старый оставлен только для проверки в виде публичного PEM
+	after := newTestIssuer(t, "RS256", "k2",
+		config.JWTKey{ID: "k1", Material: k1.public},
+		config.JWTKey{ID: "k2", Material: k2.private},
+	)
+	_, newToken, err := after.Issue(map[string]interface{}{"sub": "1"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if kid := headerKeyID(t, newToken); kid != "k2" {
+		t.Errorf("kid нового токена = %q, ожидался k2", kid)
+	}
+
+	// старый ключ выведен из оборота
+	retired := newTestIssuer(t, "RS256", "k2", config.JWTKey{ID: "k2", Material: k2.private})
+
+	tests := []struct {
+		name    string
+		issuer  *Issuer
+		token   string
+		wantErr bool
+	}{
+		{name: "старый токен до ротации", issuer: before, token: oldToken},
+		{name: "старый токен после ротации", issuer: after, token: oldToken},
+		{name: "новый токен после ротации", issuer: after, token: newToken},
+		{name: "новый токен до ротации", issuer: before, token: newToken, wantErr: true},
+		{name: "старый токен после вывода ключа", issuer: retired, token: oldToken, wantErr: true},
+		{name: "новый токен после вывода ключа", issuer: retired, token: newToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.issuer.Parse(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse ошибка = %v, ожидалась ошибка: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// signHS256 Подписывает токен HMAC с произвольным секретом и kid, как это
+// сделал бы атакующий, знающий публичный ключ
+func signHS256(t *testing.T, secret []byte, kid string) string {
+	t.Helper()
+
+	key, err := jwk.FromRaw(secret)
+	if err != nil {
+		t.Fatalf("jwk.FromRaw: %v", err)
+	}
+	if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+		t.Fatalf("Set kid: %v", err)
+	}
+
+	token := jwxjwt.New()
+	_ = token.Set(jwxjwt.SubjectKey, "1")
+	_ = token.Set(jwxjwt.IssuerKey, testIssuer)
+	_ = token.Set(jwxjwt.IssuedAtKey, time.Now())
+	_ = token.Set(jwxjwt.ExpirationKey, time.Now().Add(time.Minute))
+
+	signed, err := jwxjwt.Sign(token, jwxjwt.WithKey(jwa.HS256, key))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return string(signed)
+}
+
+// unsigned Токен с alg=none
+func unsigned(kid string) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"none","typ":"JWT","kid":"` + kid + `"}`))
+	payload := enc.EncodeToString([]byte(fmt.Sprintf(`{"sub":"1","iss":"%s","exp":%d}`,
+		testIssuer, time.Now().Add(time.Minute).Unix())))
+	return header + "." + payload + "."
+}
+
+func TestIssuerAlgConfusion(t *testing.T) {
+	rsa1, ed1 := rsaKey(t), ed25519Key(t)
+
+	rsIssuer := newTestIssuer(t, "RS256", "k1", config.JWTKey{ID: "k1", Material: rsa1.private})
+	edIssuer := newTestIssuer(t, "EdDSA", "k1", config.JWTKey{ID: "k1", Material: ed1.private})
+
+	rsaJWK, err := jwk.ParseKey(rsa1.public, jwk.WithPEM(true))
+	if err != nil {
+		t.Fatalf("ParseKey: %v", err)
+	}
+	rsaJWKJSON, err := json.Marshal(rsaJWK)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		issuer *Issuer
+		token  string
+	}{
+		{name: "RS256: HS256 с публичным PEM как секретом", issuer: rsIssuer, token: signHS256(t, rsa1.public, "k1")},
+		{name: "RS256: HS256 с публичным JWK как секретом", issuer: rsIssuer, token: signHS256(t, rsaJWKJSON, "k1")},
+		{name: "RS256: HS256 без kid", issuer: rsIssuer, token: signHS256(t, rsa1.public, "")},
+		{name: "RS256: alg=none", issuer: rsIssuer, token: unsigned("k1")},
+		{name: "EdDSA: HS256 с публичным PEM как секретом", issuer: edIssuer, token: signHS256(t, ed1.public, "k1")},
+		{name: "EdDSA: alg=none", issuer: edIssuer, token: unsigned("k1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.issuer.Parse(tt.token); err == nil {
+				t.Error("Parse принял токен с чужим алгоритмом")
+			}
+		})
+	}
+
+	t.Run("HS256: RS256 токен чужим ключом с тем же kid", func(t *testing.T) {
+		hsIssuer := newTestIssuer(t, "HS256", "k1", config.JWTKey{ID: "k1", Material: hmacKey(t).private})
+		attacker := newTestIssuer(t, "RS256", "k1", config.JWTKey{ID: "k1", Material: rsaKey(t).private})
+		attacker.issuer = hsIssuer.issuer
+
+		_, signed, err := attacker.Issue(map[string]interface{}{"sub": "1"}, time.Minute)
+		if err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+		if _, err := hsIssuer.Parse(signed); err == nil {
+			t.Error("Parse принял RS256 токен в режиме HS256")
+		}
+	})
+}
+
+func TestIssuerValidation(t *testing.T) {
+	key := config.JWTKey{ID: "k1", Material: hmacKey(t).private}
+	issuer := newTestIssuer(t, "HS256", "k1", key)
+
+	other, err := NewIssuer(config.JWT{Algorithm: "HS256", SigningKeyID: "k1", Keys: []config.JWTKey{key}, Issuer: "someone-else"})
+	if err != nil {
+		t.Fatalf("NewIssuer: %v", err)
+	}
+	_, foreign, err := other.Issue(map[string]interface{}{"sub": "1"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	now := time.Now()
+	issueAt := func(at time.Time, ttl time.Duration) string {
+		t.Helper()
+		_, signed, err := issuer.IssueAt(map[string]interface{}{"sub": "1"}, at, ttl)
+		if err != nil {
+			t.Fatalf("IssueAt: %v", err)
+		}
+		return signed
+	}
+	valid := issueAt(now, time.Minute)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "действующий токен", token: valid},
+		{name: "другой издатель", token: foreign, wantErr: jwtauth.ErrUnauthorized},
+		{name: "истек в пределах допуска", token: issueAt(now.Add(-time.Minute), time.Minute-2*time.Second)},
+		{name: "истек за пределами допуска", token: issueAt(now.Add(-time.Minute), time.Minute-10*time.Second), wantErr: jwtauth.ErrExpired},
+		{name: "iat в будущем в пределах допуска", token: issueAt(now.Add(2*time.Second), time.Minute)},
+		{name: "iat в будущем за пределами допуска", token: issueAt(now.Add(time.Minute), time.Minute), wantErr: jwtauth.ErrIATInvalid},
+		{name: "измененная подпись", token: valid[:len(valid)-2] + "AA", wantErr: jwtauth.ErrUnauthorized},
+		{name: "не токен", token: "not-a-token", wantErr: jwtauth.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := issuer.Parse(tt.token)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Parse ошибка = %v, ожидалось nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse ошибка = %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRejects(t *testing.T) {
+	rsa1 := rsaKey(t)
+
+	tests := []struct {
+		name    string
+		cnf     config.JWT
+		wantErr error
+	}{
+		{
+			name:    "неподдерживаемый алгоритм",
+			cnf:     config.JWT{Algorithm: "none", SigningKeyID: "k1", Keys: []config.JWTKey{{ID: "k1", Material: []byte("secret")}}},
+			wantErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			name:    "ключ подписи не найден",
+			cnf:     config.JWT{Algorithm: "RS256", SigningKeyID: "k2", Keys: []config.JWTKey{{ID: "k1", Material: rsa1.private}}},
+			wantErr: ErrSigningKeyNotFound,
+		},
+		{
+			name:    "тип ключа не подходит",
+			cnf:     config.JWT{Algorithm: "EdDSA", SigningKeyID: "k1", Keys: []config.JWTKey{{ID: "k1", Material: rsa1.private}}},
+			wantErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			name: "публичный ключ подписи",
+			cnf:  config.JWT{Algorithm: "RS256", SigningKeyID: "k1", Keys: []config.JWTKey{{ID: "k1", Material: rsa1.public}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIssuer(tt.cnf)
+			if err == nil {
+				t.Fatal("NewIssuer без ошибки")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewIssuer ошибка = %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	rsa1, rsa2 := rsaKey(t), rsaKey(t)
+	secret := hmacKey(t)
+
+	tests := []struct {
+		name     string
+		issuer   *Issuer
+		wantKIDs []string
+	}{
+		{
+			name: "RS256",
+			issuer: newTestIssuer(t, "RS256", "k2",
+				config.JWTKey{ID: "k1", Material: rsa1.public},
+				config.JWTKey{ID: "k2", Material: rsa2.private},
+			),
+			wantKIDs: []string{"k1", "k2"},
+		},
+		{
+			name:   "HS256",
+			issuer: newTestIssuer(t, "HS256", "k1", config.JWTKey{ID: "k1", Material: secret.private}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.issuer.JWKSHandler()(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("статус %d", rec.Code)
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=300" {
+				t.Errorf("Cache-Control = %q", cc)
+			}
+			body := rec.Body.Bytes()
+			for _, private := range []string{`"d"`, `"p"`, `"q"`, `"k"`} {
+				if strings.Contains(string(body), private+":") {
+					t.Errorf("в JWKS есть приватное поле %s: %s", private, body)
+				}
+			}
+
+			set, err := jwk.Parse(body)
+			if err != nil {
+				t.Fatalf("jwk.Parse: %v", err)
+			}
+			if set.Len() != len(tt.wantKIDs) {
+				t.Fatalf("ключей в JWKS %d, ожидалось %d", set.Len(), len(tt.wantKIDs))
+			}
+			for _, kid := range tt.wantKIDs {
+				key, ok := set.LookupKeyID(kid)
+				if !ok {
+					t.Errorf("нет ключа %s", kid)
+					continue
+				}
+				if key.Algorithm() != jwa.RS256 {
+					t.Errorf("alg ключа %s = %s", kid, key.Algorithm())
+				}
+				if isPrivate, _ := jwk.IsPrivateKey(key); isPrivate {
+					t.Errorf("ключ %s приватный", kid)
+				}
+			}
+		})
+	}
+
+	// токен проверяется по опубликованному набору без доступа к сервису
+	issuer := tests[0].issuer
+	_, signed, err := issuer.Issue(map[string]interface{}{"sub": "1"}, time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	issuer.JWKSHandler()(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	set, err := jwk.Parse(rec.Body.Bytes())
+	if err != nil {
+		t.Fatalf("jwk.Parse: %v", err)
+	}
+	if _, err := jwxjwt.Parse([]byte(signed), jwxjwt.WithKeySet(set)); err != nil {
+		t.Errorf("токен не проверяется по JWKS: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileMailer Для локальной разработки и тестов: складывает письма файлами в dir.
+// Текст письма содержит одноразовые ссылки, поэтому в лог он не попадает, а при
+// пустом dir письмо отбрасывается
+type FileMailer struct {
+	log *slog.Logger
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFileMailer(log *slog.Logger, dir string) *FileMailer {
+	return &FileMailer{log: log, dir: dir}
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	const op = "mailer.FileMailer.Send"
+
+	if m.dir == "" {
+		m.log.Info("Письмо не сохранено, каталог не задан", slog.String("to", msg.To), slog.String("subject", msg.Subject))
+		return nil
+	}
+
+	m.mu.Lock()
+	m.seq++
+	seq := m.seq
+	m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	name := fmt.Sprintf("%s_%03d_%s.eml", time.Now().Format("20060102T150405"), seq, sanitize(msg.To))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	m.log.Info("Письмо сохранено", slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("file", path))
+
+	return nil
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,16 @@
+package mailer
+
+import (
+	"context"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer Доставка писем пользователям
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
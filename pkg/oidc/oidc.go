@@ -0,0 +1,293 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"task-manager/internal/config"
+	"time"
+)
+
+var (
+	ErrDiscovery      = errors.New("не удалось получить настройки OIDC провайдера")
+	ErrExchange       = errors.New("провайдер отклонил код авторизации")
+	ErrInvalidIDToken = errors.New("невалидный id_token")
+	ErrNonceMismatch  = errors.New("nonce в id_token не совпадает")
+	ErrMissingIDToken = errors.New("провайдер не вернул id_token")
+	ErrMissingSubject = errors.New("в id_token нет sub")
+)
+
+const (
+	maxResponseBytes   = 1 << 20
+	clockSkewTolerance = time.Minute
+)
+
+// Claims Данные пользователя из id_token
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider Клиент OpenID Connect для authorization code flow с PKCE.
+// Настройки провайдера и его ключи загружаются при первом обращении,
+// поэтому недоступный провайдер не мешает запуску сервиса
+type Provider struct {
+	cnf        config.OIDC
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      jwk.Set
+}
+
+func NewProvider(cnf config.OIDC) *Provider {
+	return &Provider{
+		cnf:        cnf,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthCodeURL Адрес страницы входа провайдера
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	const op = "pkg.oidc.AuthCodeURL"
+
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cnf.OIDCClientID},
+		"redirect_uri":          {p.cnf.OIDCRedirectURL},
+		"scope":                 {strings.Join(p.cnf.OIDCScopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange Меняет код авторизации на id_token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	const op = "pkg.oidc.Exchange"
+
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cnf.OIDCRedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.cnf.OIDCClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cnf.OIDCClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cnf.OIDCClientID), url.QueryEscape(p.cnf.OIDCClientSecret))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%s: статус %d: %w", op, resp.StatusCode, ErrExchange)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("%s: %s %s: %w", op, body.Error, body.ErrorDescription, ErrExchange)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("%s: %w", op, ErrMissingIDToken)
+	}
+
+	return body.IDToken, nil
+}
+
+// VerifyIDToken Проверяет подпись, издателя, получателя, срок и nonce id_token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	const op = "pkg.oidc.VerifyIDToken"
+
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := p.parse(ctx, rawIDToken, d)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidIDToken, err)
+	}
+
+	claims := token.PrivateClaims()
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%s: %w", op, ErrNonceMismatch)
+	}
+	if token.Subject() == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrMissingSubject)
+	}
+
+	result := &Claims{Subject: token.Subject()}
+	result.Email, _ = claims["email"].(string)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	result.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	return result, nil
+}
+
+// parse Проверяет токен по ключам провайдера. Если подходящего ключа нет,
+// ключи перечитываются один раз: провайдер мог их ротировать
+func (p *Provider) parse(ctx context.Context, rawIDToken string, d *discovery) (jwt.Token, error) {
+	keys, err := p.getKeys(ctx, d, false)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.parseWithKeys(rawIDToken, d, keys)
+	if err == nil || !unknownKey(rawIDToken, keys) {
+		return token, err
+	}
+
+	keys, refreshErr := p.getKeys(ctx, d, true)
+	if refreshErr != nil {
+		return nil, err
+	}
+	return p.parseWithKeys(rawIDToken, d, keys)
+}
+
+func (p *Provider) parseWithKeys(rawIDToken string, d *discovery, keys jwk.Set) (jwt.Token, error) {
+	return jwt.ParseString(rawIDToken,
+		jwt.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cnf.OIDCClientID),
+		jwt.WithAcceptableSkew(clockSkewTolerance),
+		jwt.WithValidate(true),
+	)
+}
+
+// unknownKey Подписан ли токен ключом, которого нет в наборе
+func unknownKey(rawIDToken string, keys jwk.Set) bool {
+	msg, err := jws.ParseString(rawIDToken)
+	if err != nil || len(msg.Signatures()) == 0 {
+		return false
+	}
+
+	kid := msg.Signatures()[0].ProtectedHeaders().KeyID()
+	if kid == "" {
+		return false
+	}
+	_, found := keys.LookupKeyID(kid)
+	return !found
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d discovery
+	if err := p.getJSON(ctx, p.cnf.OIDCIssuerURL+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+	if d.Issuer != p.cnf.OIDCIssuerURL {
+		return nil, fmt.Errorf("%w: issuer %q не совпадает с %q", ErrDiscovery, d.Issuer, p.cnf.OIDCIssuerURL)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: неполные настройки провайдера", ErrDiscovery)
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *Provider) getKeys(ctx context.Context, d *discovery, refresh bool) (jwk.Set, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && !refresh {
+		return p.keys, nil
+	}
+
+	keys, err := jwk.Fetch(ctx, d.JWKSURI, jwk.WithHTTPClient(p.httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = keys
+	return p.keys, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("статус %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(v)
+}
+
+// CodeChallenge S256-преобразование code_verifier для PKCE (RFC 7636)
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
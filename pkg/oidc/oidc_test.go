@@ -0,0 +1,477 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"task-manager/internal/config"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	testClientID     = "task-manager"
+	testClientSecret = "client-secret"
+	testRedirectURL  = "http://localhost/oidc/callback"
+)
+
+// fakeProvider OIDC провайдер на httptest: discovery, JWKS и token endpoint с
+// проверкой PKCE. Код авторизации выдается тестом через authorize
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	issuer string
+
+	mu         sync.Mutex
+	signKey    jwk.Key
+	published  jwk.Set
+	challenges map[string]string // код -> code_challenge
+	idTokens   map[string]string // код -> id_token
+
+	discoveryHits atomic.Int32
+	jwksHits      atomic.Int32
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+
+	f := &fakeProvider{
+		t:          t,
+		challenges: make(map[string]string),
+		idTokens:   make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", f.handleDiscovery)
+	mux.HandleFunc("/jwks", f.handleJWKS)
+	mux.HandleFunc("/token", f.handleToken)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	f.issuer = f.server.URL
+	f.rotate("key-1")
+	return f
+}
+
+// rotate Выпускает новый ключ подписи и публикует только его
+func (f *fakeProvider) rotate(kid string) {
+	f.t.Helper()
+
+	key := newSigningKey(f.t, kid)
+	public, err := jwk.PublicSetOf(singleKeySet(f.t, key))
+	if err != nil {
+		f.t.Fatalf("PublicSetOf: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signKey = key
+	f.published = public
+}
+
+func newSigningKey(t *testing.T, kid string) jwk.Key {
+	t.Helper()
+
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		t.Fatalf("jwk.FromRaw: %v", err)
+	}
+	_ = key.Set(jwk.KeyIDKey, kid)
+	_ = key.Set(jwk.AlgorithmKey, jwa.RS256)
+	return key
+}
+
+func singleKeySet(t *testing.T, key jwk.Key) jwk.Set {
+	t.Helper()
+
+	set := jwk.NewSet()
+	if err := set.AddKey(key); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	return set
+}
+
+func (f *fakeProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	f.discoveryHits.Add(1)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 f.issuer,
+		"authorization_endpoint": f.server.URL + "/authorize",
+		"token_endpoint":         f.server.URL + "/token",
+		"jwks_uri":               f.server.URL + "/jwks",
+	})
+}
+
+func (f *fakeProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	f.jwksHits.Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writeJSON(w, http.StatusOK, f.published)
+}
+
+func (f *fakeProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != testRedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	f.mu.Lock()
+	code := r.PostForm.Get("code")
+	challenge, known := f.challenges[code]
+	idToken := f.idTokens[code]
+	delete(f.challenges, code)
+	f.mu.Unlock()
+
+	if !known {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown code"})
+		return
+	}
+	if CodeChallenge(r.PostForm.Get("code_verifier")) != challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": idToken})
+}
+
+// authorize Имитирует вход пользователя на странице провайдера: запоминает
+// code_challenge из адреса входа и выдает код
+func (f *fakeProvider) authorize(authURL, code, idToken string) {
+	f.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatalf("url.Parse: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.challenges[code] = u.Query().Get("code_challenge")
+	f.idTokens[code] = idToken
+}
+
+// idToken Подписывает id_token текущим ключом провайдера, claims дополняют и
+// переопределяют стандартные
+func (f *fakeProvider) idToken(claims map[string]any) string {
+	f.t.Helper()
+
+	f.mu.Lock()
+	key := f.signKey
+	f.mu.Unlock()
+	return signIDToken(f.t, key, f.issuer, claims)
+}
+
+func signIDToken(t *testing.T, key jwk.Key, issuer string, claims map[string]any) string {
+	t.Helper()
+
+	now := time.Now()
+	token := jwt.New()
+	defaults := map[string]any{
+		jwt.IssuerKey:     issuer,
+		jwt.AudienceKey:   []string{testClientID},
+		jwt.SubjectKey:    "provider-user-1",
+		jwt.IssuedAtKey:   now,
+		jwt.ExpirationKey: now.Add(5 * time.Minute),
+		"nonce":           "nonce-1",
+	}
+	for k, v := range defaults {
+		if _, overridden := claims[k]; !overridden {
+			_ = token.Set(k, v)
+		}
+	}
+	for k, v := range claims {
+		if err := token.Set(k, v); err != nil {
+			t.Fatalf("Set %s: %v", k, err)
+		}
+	}
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.RS256, key))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return string(signed)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeProvider) newProvider() *Provider {
+	return NewProvider(config.OIDC{
+		OIDCProviderName: "fake",
+		OIDCIssuerURL:    f.issuer,
+		OIDCClientID:     testClientID,
+		OIDCClientSecret: testClientSecret,
+		OIDCRedirectURL:  testRedirectURL,
+		OIDCScopes:       []string{"openid", "email", "profile"},
+	})
+}
+
+func TestDiscovery(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.newProvider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != fake.server.URL+"/authorize" {
+		t.Errorf("адрес входа %s", got)
+	}
+
+	query := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier-1"),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s = %q, ожидалось %q", key, got, value)
+		}
+	}
+	if query.Get("code_challenge") == "verifier-1" {
+		t.Error("code_verifier передан провайдеру в открытом виде")
+	}
+
+	// настройки запрашиваются один раз
+	if _, err := provider.AuthCodeURL(ctx, "state-2", "nonce-2", "verifier-2"); err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if hits := fake.discoveryHits.Load(); hits != 1 {
+		t.Errorf("discovery запрошен %d раз, ожидался 1", hits)
+	}
+}
+
+func TestDiscoveryRejects(t *testing.T) {
+	fake := newFakeProvider(t)
+	fake.issuer = "https://evil.example"
+
+	tests := []struct {
+		name      string
+		issuerURL string
+	}{
+		{name: "issuer не совпадает", issuerURL: fake.server.URL},
+		{name: "настройки не найдены", issuerURL: fake.server.URL + "/missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewProvider(config.OIDC{OIDCIssuerURL: tt.issuerURL, OIDCClientID: testClientID})
+			if _, err := provider.AuthCodeURL(context.Background(), "s", "n", "v"); !errors.Is(err, ErrDiscovery) {
+				t.Errorf("AuthCodeURL ошибка = %v, ожидалась %v", err, ErrDiscovery)
+			}
+		})
+	}
+}
+
+func TestExchangePKCE(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.newProvider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	issued := fake.idToken(nil)
+
+	tests := []struct {
+		name     string
+		code     string
+		verifier string
+		idToken  string
+		wantErr  error
+	}{
+		{name: "верный code_verifier", code: "code-1", verifier: "verifier-1", idToken: issued},
+		{name: "чужой code_verifier", code: "code-2", verifier: "verifier-2", idToken: issued, wantErr: ErrExchange},
+		{name: "провайдер не вернул id_token", code: "code-3", verifier: "verifier-1", wantErr: ErrMissingIDToken},
+		{name: "неизвестный код", code: "code-unknown", verifier: "verifier-1", wantErr: ErrExchange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.code != "code-unknown" {
+				fake.authorize(authURL, tt.code, tt.idToken)
+			}
+
+			idToken, err := provider.Exchange(ctx, tt.code, tt.verifier)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Exchange ошибка = %v, ожидалась %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if idToken != tt.idToken {
+				t.Error("Exchange вернул не тот id_token")
+			}
+		})
+	}
+
+	t.Run("код одноразовый", func(t *testing.T) {
+		fake.authorize(authURL, "code-once", issued)
+		if _, err := provider.Exchange(ctx, "code-once", "verifier-1"); err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if _, err := provider.Exchange(ctx, "code-once", "verifier-1"); !errors.Is(err, ErrExchange) {
+			t.Errorf("повторный Exchange ошибка = %v, ожидалась %v", err, ErrExchange)
+		}
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.newProvider()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		nonce   string
+		wantErr error
+		want    *Claims
+	}{
+		{
+			name: "действующий токен",
+			claims: map[string]any{
+				"email": "user@example.com", "email_verified": true,
+				"preferred_username": "user", "name": "Test User",
+			},
+			nonce: "nonce-1",
+			want: &Claims{
+				Subject: "provider-user-1", Email: "user@example.com", EmailVerified: true,
+				PreferredUsername: "user", Name: "Test User",
+			},
+		},
+		{
+			name:   "email_verified строкой",
+			claims: map[string]any{"email": "user@example.com", "email_verified": "true"},
+			nonce:  "nonce-1",
+			want:   &Claims{Subject: "provider-user-1", Email: "user@example.com", EmailVerified: true},
+		},
+		{name: "nonce не совпадает", nonce: "nonce-2", wantErr: ErrNonceMismatch},
+		{name: "пустой nonce", claims: map[string]any{"nonce": ""}, nonce: "nonce-1", wantErr: ErrNonceMismatch},
+		{name: "другой получатель", claims: map[string]any{jwt.AudienceKey: []string{"other-client"}}, nonce: "nonce-1", wantErr: ErrInvalidIDToken},
+		{name: "другой издатель", claims: map[string]any{jwt.IssuerKey: "https://evil.example"}, nonce: "nonce-1", wantErr: ErrInvalidIDToken},
+		{
+			name:    "истек",
+			claims:  map[string]any{jwt.IssuedAtKey: time.Now().Add(-time.Hour), jwt.ExpirationKey: time.Now().Add(-10 * time.Minute)},
+			nonce:   "nonce-1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{name: "нет sub", claims: map[string]any{jwt.SubjectKey: ""}, nonce: "nonce-1", wantErr: ErrMissingSubject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.VerifyIDToken(ctx, fake.idToken(tt.claims), tt.nonce)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("VerifyIDToken ошибка = %v, ожидалась %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken: %v", err)
+			}
+			if *claims != *tt.want {
+				t.Errorf("claims = %+v, ожидалось %+v", *claims, *tt.want)
+			}
+		})
+	}
+
+	t.Run("подпись ключом не из JWKS", func(t *testing.T) {
+		forged := signIDToken(t, newSigningKey(t, "key-1"), fake.issuer, nil)
+		if _, err := provider.VerifyIDToken(ctx, forged, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("VerifyIDToken ошибка = %v, ожидалась %v", err, ErrInvalidIDToken)
+		}
+	})
+}
+
+func TestVerifyIDTokenUnknownKid(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.newProvider()
+	ctx := context.Background()
+
+	if _, err := provider.VerifyIDToken(ctx, fake.idToken(nil), "nonce-1"); err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if hits := fake.jwksHits.Load(); hits != 1 {
+		t.Fatalf("JWKS запрошен %d раз, ожидался 1", hits)
+	}
+
+	// провайдер ротировал ключ: неизвестный kid приводит к одному перечитыванию JWKS
+	fake.rotate("key-2")
+	if _, err := provider.VerifyIDToken(ctx, fake.idToken(nil), "nonce-1"); err != nil {
+		t.Fatalf("VerifyIDToken после ротации: %v", err)
+	}
+	if hits := fake.jwksHits.Load(); hits != 2 {
+		t.Errorf("JWKS запрошен %d раз, ожидалось 2", hits)
+	}
+
+	// kid, которого нет и в свежем наборе: токен отклоняется после одного перечитывания
+	unknown := signIDToken(t, newSigningKey(t, "key-unknown"), fake.issuer, nil)
+	_, err := provider.VerifyIDToken(ctx, unknown, "nonce-1")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken ошибка = %v, ожидалась %v", err, ErrInvalidIDToken)
+	}
+	if hits := fake.jwksHits.Load(); hits != 3 {
+		t.Errorf("JWKS запрошен %d раз, ожидалось 3", hits)
+	}
+
+	// известный kid с неверной подписью не вызывает перечитывания
+	forged := signIDToken(t, newSigningKey(t, "key-2"), fake.issuer, nil)
+	if _, err := provider.VerifyIDToken(ctx, forged, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken ошибка = %v, ожидалась %v", err, ErrInvalidIDToken)
+	}
+	if hits := fake.jwksHits.Load(); hits != 3 {
+		t.Errorf("JWKS запрошен %d раз, ожидалось 3", hits)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// пример из RFC 7636, приложение B
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallenge = %s, ожидалось %s", got, want)
+	}
+	if strings.ContainsAny(got, "+/=") {
+		t.Errorf("CodeChallenge не base64url без дополнения: %s", got)
+	}
+}
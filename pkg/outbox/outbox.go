@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"task-manager/pkg/clients/posgresql"
+	"task-manager/pkg/events"
+)
+
+// Store Записывает события в таблицу outbox вместо прямой отправки в Kafka.
+// Если в ctx открыта транзакция (posgresql.Transactor), событие попадает в нее
+// и фиксируется вместе с доменным изменением или не фиксируется вовсе.
+// В Kafka события отправляет Relay
+type Store struct {
+	db posgresql.DBClient
+}
+
+func NewStore(db posgresql.DBClient) *Store {
+	return &Store{db: db}
+}
+
+// Publish Сохраняет событие для последующей отправки. Реализует Producer сервисов
+func (s *Store) Publish(ctx context.Context, event *events.Event) error {
+	const op = "pkg.outbox.Publish"
+
+	envelope, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt := `
+	INSERT INTO outbox (event_id, event_type, aggregate_key, event)
+	VALUES ($1, $2, $3, $4)
+	`
+	if _, err := posgresql.Conn(ctx, s.db).Exec(ctx, stmt, event.ID, event.Type, event.Key, envelope); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"task-manager/pkg/clients/posgresql"
+	"task-manager/pkg/events"
+	"task-manager/pkg/logger/sl"
+	"time"
+)
+
+// relayLockID Ключ advisory-блокировки: пачку отправляет только один экземпляр
+// приложения, иначе события одного ключа могли бы уйти не по порядку
+const relayLockID = 7_362_114_001
+
+// Publisher Отправка события в брокер, реализация в pkg/clients/kafka
+type Publisher interface {
+	Publish(ctx context.Context, event *events.Event) error
+}
+
+// RelayPolicy Параметры отправки. Задержка после неудачи растет от BackoffBase
+// вдвое с каждой попыткой, но не больше BackoffMax
+type RelayPolicy struct {
+	Interval        time.Duration
+	BatchSize       int
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	Retention       time.Duration
+	CleanupInterval time.Duration
+}
+
+// Relay Переносит события из outbox в брокер. Доставка "хотя бы один раз":
+// если отметка об отправке не зафиксировалась, событие уйдет повторно, поэтому
+// потребители отбрасывают повторы по id события. События с одним ключом
+// отправляются в порядке фиксации транзакций, записавших их: пока раннее
+// событие ждет повтора, следующие за ним не отправляются
+type Relay struct {
+	logger    *slog.Logger
+	db        posgresql.DBClient
+	publisher Publisher
+	policy    RelayPolicy
+}
+
+func NewRelay(logger *slog.Logger, db posgresql.DBClient, publisher Publisher, policy RelayPolicy) *Relay {
+	return &Relay{
+		logger:    logger,
+		db:        db,
+		publisher: publisher,
+		policy:    policy,
+	}
+}
+
+type pendingEvent struct {
+	id       int64
+	key      string
+	attempts int
+	envelope []byte
+}
+
+// Run Отправляет накопленные события и удаляет старые отправленные, пока не отменен ctx
+func (r *Relay) Run(ctx context.Context) {
+	const op = "pkg.outbox.Run"
+	log := r.logger.With(slog.String("op", op))
+
+	relayTicker := time.NewTicker(r.policy.Interval)
+	defer relayTicker.Stop()
+	cleanupTicker := time.NewTicker(r.policy.CleanupInterval)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-relayTicker.C:
+			// пачка заполнена целиком - вероятно, в очереди есть еще события.
+			// Начатая пачка доводится до конца и при остановке, иначе уже
+			// отправленные события не получат отметку и уйдут повторно
+			for ctx.Err() == nil {
+				processed, err := r.relayBatch(context.WithoutCancel(ctx))
+				if err != nil {
+					log.Error("Ошибка отправки событий из outbox", sl.Err(err))
+					break
+				}
+				if processed == 0 || processed < r.policy.BatchSize {
+					break
+				}
+			}
+		case <-cleanupTicker.C:
+			deleted, err := r.cleanup(ctx)
+			if err != nil {
+				log.Error("Ошибка очистки outbox", sl.Err(err))
+				continue
+			}
+			if deleted > 0 {
+				log.Info("Удалены отправленные события", slog.Int64("count", deleted))
+			}
+		}
+	}
+}
+
+// relayBatch Отправляет одну пачку готовых к отправке событий и возвращает,
+// сколько из них было обработано
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	const op = "pkg.outbox.relayBatch"
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, relayLockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	if err := assignSequence(ctx, tx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// событие не берется, если раньше него с тем же ключом стоит событие, ждущее повтора
+	stmt := `
+	SELECT o.id, o.aggregate_key, o.attempts, o.event
+	FROM outbox o
+	WHERE o.sent_at IS NULL AND o.relay_seq IS NOT NULL AND o.next_attempt_at <= NOW()
+	  AND NOT EXISTS (
+	      SELECT 1 FROM outbox p
+	      WHERE p.sent_at IS NULL AND p.next_attempt_at > NOW()
+	        AND o.aggregate_key <> '' AND p.aggregate_key = o.aggregate_key AND p.relay_seq < o.relay_seq
+	  )
+	ORDER BY o.relay_seq
+	LIMIT $1
+	`
+	rows, err := tx.Query(ctx, stmt, r.policy.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	var batch []pendingEvent
+	for rows.Next() {
+		var p pendingEvent
+		if err := rows.Scan(&p.id, &p.key, &p.attempts, &p.envelope); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		batch = append(batch, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	failedKeys := make(map[string]bool)
+	for _, p := range batch {
+		if p.key != "" && failedKeys[p.key] {
+			continue
+		}
+
+		if err := r.publish(ctx, p); err != nil {
+			failedKeys[p.key] = true
+			delay := r.backoff(p.attempts)
+			r.logger.Warn("Событие не отправлено, повтор позже",
+				slog.String("op", op),
+				slog.Int64("outbox_id", p.id),
+				slog.Int("attempts", p.attempts+1),
+				slog.Duration("retry_in", delay),
+				sl.Err(err),
+			)
+
+			stmt := `
+			UPDATE outbox
+			SET attempts = attempts + 1, last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3)
+			WHERE id = $1
+			`
+			if _, err := tx.Exec(ctx, stmt, p.id, err.Error(), delay.Seconds()); err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		stmt := `UPDATE outbox SET sent_at = NOW(), attempts = attempts + 1, last_error = '' WHERE id = $1`
+		if _, err := tx.Exec(ctx, stmt, p.id); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return len(batch), nil
+}
+
+// assignSequence Нумерует события, ставшие видимыми с прошлой пачки. id выдается
+// при вставке, и транзакция с меньшим id может зафиксироваться позже: отправка
+// по id выпустила бы событие, а потом более раннее событие того же ключа.
+// Номер выдается под advisory-блокировкой и только зафиксированным событиям,
+// поэтому его порядок совпадает с порядком фиксации. События, зафиксированные
+// одновременно, нумеруются по id
+func assignSequence(ctx context.Context, tx pgx.Tx) error {
+	stmt := `
+	WITH fresh AS (
+	    SELECT id, nextval('outbox_relay_seq') AS seq
+	    FROM (SELECT id FROM outbox WHERE relay_seq IS NULL ORDER BY id) ids
+	)
+	UPDATE outbox o SET relay_seq = fresh.seq
+	FROM fresh
+	WHERE o.id = fresh.id
+	`
+	_, err := tx.Exec(ctx, stmt)
+	return err
+}
+
+func (r *Relay) publish(ctx context.Context, p pendingEvent) error {
+	var event events.Event
+	if err := json.Unmarshal(p.envelope, &event); err != nil {
+		return err
+	}
+	event.Key = p.key
+
+	return r.publisher.Publish(ctx, &event)
+}
+
+// backoff Задержка перед следующей попыткой после attempts неудачных
+func (r *Relay) backoff(attempts int) time.Duration {
+	if attempts >= 30 {
+		return r.policy.BackoffMax
+	}
+	delay := r.policy.BackoffBase << attempts
+	if delay <= 0 || delay > r.policy.BackoffMax {
+		return r.policy.BackoffMax
+	}
+	return delay
+}
+
+// cleanup Удаляет события, отправленные раньше срока хранения
+func (r *Relay) cleanup(ctx context.Context) (int64, error) {
+	const op = "pkg.outbox.cleanup"
+
+	stmt := `DELETE FROM outbox WHERE sent_at IS NOT NULL AND sent_at < NOW() - make_interval(secs => $1)`
+	tag, err := r.db.Exec(ctx, stmt, r.policy.Retention.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
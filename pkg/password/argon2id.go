@@ -0,0 +1,118 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Верхние границы параметров. Хеш с параметрами больше этих считается поврежденным:
+// иначе подмененный хеш заставил бы сервер тратить гигабайты памяти на проверку
+const (
+	argon2MaxMemory  = 1 << 20 // 1 ГиБ
+	argon2MaxTime    = 100
+	argon2MaxKeySize = 1024
+)
+
+// Argon2idParams Memory в КиБ, Time - число проходов
+type Argon2idParams struct {
+	Memory     uint32
+	Time       uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+// Argon2id Хеши в формате PHC: $argon2id$v=19$m=65536,t=3,p=2$<соль>$<ключ>
+type Argon2id struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) (*Argon2id, error) {
+	if !params.valid() {
+		return nil, fmt.Errorf("argon2id %+v: %w", params, ErrIncompatibleParam)
+	}
+	return &Argon2id{params: params}, nil
+}
+
+func (p Argon2idParams) valid() bool {
+	return p.Time >= 1 && p.Time <= argon2MaxTime &&
+		p.Threads >= 1 &&
+		p.Memory >= 8*uint32(p.Threads) && p.Memory <= argon2MaxMemory &&
+		p.SaltLength >= 8 && p.SaltLength <= argon2MaxKeySize &&
+		p.KeyLength >= 16 && p.KeyLength <= argon2MaxKeySize
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Time, a.params.Memory, a.params.Threads, a.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version,
+		a.params.Memory, a.params.Time, a.params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Verify(hash, password string) (bool, error) {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (a *Argon2id) NeedsRehash(hash string) bool {
+	params, _, _, err := parseArgon2id(hash)
+	return err != nil || params != a.params
+}
+
+func (a *Argon2id) Handles(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func parseArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", соль, ключ
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrMalformedHash
+	}
+
+	var params Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return Argon2idParams{}, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, ErrMalformedHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	if !params.valid() {
+		return Argon2idParams{}, nil, nil, ErrMalformedHash
+	}
+	return params, salt, key, nil
+}
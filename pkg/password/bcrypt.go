@@ -0,0 +1,62 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// bcryptMaxLength bcrypt учитывает только первые 72 байта пароля
+const bcryptMaxLength = 72
+
+type Bcrypt struct {
+	cost int
+}
+
+func NewBcrypt(cost int) (*Bcrypt, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost %d: %w", cost, ErrIncompatibleParam)
+	}
+	return &Bcrypt{cost: cost}, nil
+}
+
+// Hash Отказывает в паролях длиннее 72 байт вместо молчаливого усечения
+func (b *Bcrypt) Hash(password string) (string, error) {
+	if len(password) > bcryptMaxLength {
+		return "", ErrPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify Пароль длиннее 72 байт не подходит никогда: иначе bcrypt сравнил бы
+// только его начало
+func (b *Bcrypt) Verify(hash, password string) (bool, error) {
+	if len(password) > bcryptMaxLength {
+		return false, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword), errors.Is(err, bcrypt.ErrPasswordTooLong):
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %w", ErrMalformedHash, err)
+	}
+}
+
+func (b *Bcrypt) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.cost
+}
+
+func (b *Bcrypt) Handles(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,87 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"task-manager/internal/config"
+)
+
+var (
+	ErrUnknownHash       = errors.New("неизвестный формат хеша пароля")
+	ErrUnknownAlgorithm  = errors.New("неизвестный алгоритм хеширования паролей")
+	ErrPasswordTooLong   = errors.New("пароль слишком длинный для алгоритма хеширования")
+	ErrMalformedHash     = errors.New("поврежденный хеш пароля")
+	ErrIncompatibleParam = errors.New("недопустимые параметры хеширования")
+)
+
+// Algorithm Один алгоритм хеширования. Хеш начинается с префикса алгоритма,
+// поэтому по нему всегда можно понять, чем он посчитан
+type Algorithm interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash хеш посчитан этим алгоритмом, но с устаревшими параметрами
+	NeedsRehash(hash string) bool
+	// Handles хеш посчитан этим алгоритмом
+	Handles(hash string) bool
+}
+
+// Hasher Хеширует текущим алгоритмом из конфига и проверяет хеши всех известных
+// алгоритмов, чтобы старые пароли продолжали работать после смены настроек
+type Hasher struct {
+	current Algorithm
+	known   []Algorithm
+}
+
+func NewHasher(cnf config.PasswordHashing) (*Hasher, error) {
+	const op = "pkg.password.NewHasher"
+
+	bcryptAlg, err := NewBcrypt(cnf.BcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	argonAlg, err := NewArgon2id(Argon2idParams{
+		Memory:     cnf.Argon2Memory,
+		Time:       cnf.Argon2Time,
+		Threads:    cnf.Argon2Threads,
+		SaltLength: cnf.Argon2SaltLength,
+		KeyLength:  cnf.Argon2KeyLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	hasher := &Hasher{known: []Algorithm{argonAlg, bcryptAlg}}
+	switch cnf.PasswordHashAlgorithm {
+	case "argon2id":
+		hasher.current = argonAlg
+	case "bcrypt":
+		hasher.current = bcryptAlg
+	default:
+		return nil, fmt.Errorf("%s: %s: %w", op, cnf.PasswordHashAlgorithm, ErrUnknownAlgorithm)
+	}
+
+	return hasher, nil
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify Пустой хеш у аккаунтов без локального пароля, созданных при входе
+// через внешнего провайдера: такой пароль не подходит никогда
+func (h *Hasher) Verify(hash, password string) (bool, error) {
+	if hash == "" {
+		return false, nil
+	}
+	for _, alg := range h.known {
+		if alg.Handles(hash) {
+			return alg.Verify(hash, password)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+// NeedsRehash Хеш посчитан другим алгоритмом или с другими параметрами, чем текущие
+func (h *Hasher) NeedsRehash(hash string) bool {
+	return !h.current.Handles(hash) || h.current.NeedsRehash(hash)
+}
@@ -0,0 +1,281 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"task-manager/internal/config"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams Минимальные параметры, чтобы тесты шли быстро
+var testParams = Argon2idParams{Memory: 64, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32}
+
+func testHasher(t *testing.T, algorithm string) *Hasher {
+	t.Helper()
+
+	hasher, err := NewHasher(config.PasswordHashing{
+		PasswordHashAlgorithm: algorithm,
+		BcryptCost:            bcrypt.MinCost,
+		Argon2Memory:          testParams.Memory,
+		Argon2Time:            testParams.Time,
+		Argon2Threads:         testParams.Threads,
+		Argon2SaltLength:      testParams.SaltLength,
+		Argon2KeyLength:       testParams.KeyLength,
+	})
+	if err != nil {
+		t.Fatalf("NewHasher(%s): %v", algorithm, err)
+	}
+	return hasher
+}
+
+func phc(version, params string, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$%s$%s$%s$%s",
+		version, params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	alg, err := NewArgon2id(testParams)
+	if err != nil {
+		t.Fatalf("NewArgon2id: %v", err)
+	}
+
+	hash, err := alg.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("неожиданный формат хеша: %s", hash)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "верный пароль", password: "correct horse", want: true},
+		{name: "неверный пароль", password: "correct horsE", want: false},
+		{name: "пустой пароль", password: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := alg.Verify(hash, tt.password)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if ok != tt.want {
+				t.Errorf("Verify = %v, ожидалось %v", ok, tt.want)
+			}
+		})
+	}
+
+	if alg.NeedsRehash(hash) {
+		t.Error("NeedsRehash = true для хеша с текущими параметрами")
+	}
+
+	stronger, err := NewArgon2id(Argon2idParams{Memory: 128, Time: 2, Threads: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatalf("NewArgon2id: %v", err)
+	}
+	if !stronger.NeedsRehash(hash) {
+		t.Error("NeedsRehash = false после смены параметров")
+	}
+}
+
+func TestArgon2idMalformedHash(t *testing.T) {
+	alg, err := NewArgon2id(testParams)
+	if err != nil {
+		t.Fatalf("NewArgon2id: %v", err)
+	}
+
+	salt := []byte("0123456789abcdef")
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "мало частей", hash: "$argon2id$v=19$m=64,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt)},
+		{name: "лишняя часть", hash: phc("v=19", "m=64,t=1,p=1", salt, key) + "$extra"},
+		{name: "другой алгоритм", hash: strings.Replace(phc("v=19", "m=64,t=1,p=1", salt, key), "argon2id", "argon2i", 1)},
+		{name: "другая версия", hash: phc("v=16", "m=64,t=1,p=1", salt, key)},
+		{name: "версия не число", hash: phc("v=x", "m=64,t=1,p=1", salt, key)},
+		{name: "параметры не разбираются", hash: phc("v=19", "m=64;t=1;p=1", salt, key)},
+		{name: "соль не base64", hash: "$argon2id$v=19$m=64,t=1,p=1$!!!$" + base64.RawStdEncoding.EncodeToString(key)},
+		{name: "ключ не base64", hash: "$argon2id$v=19$m=64,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$!!!"},
+		{name: "пустой ключ", hash: phc("v=19", "m=64,t=1,p=1", salt, nil)},
+		{name: "короткая соль", hash: phc("v=19", "m=64,t=1,p=1", salt[:4], key)},
+		{name: "t=0", hash: phc("v=19", "m=64,t=0,p=1", salt, key)},
+		{name: "p=0", hash: phc("v=19", "m=64,t=1,p=0", salt, key)},
+		{name: "p больше uint8", hash: phc("v=19", "m=64,t=1,p=300", salt, key)},
+		{name: "память меньше 8 на поток", hash: phc("v=19", "m=8,t=1,p=4", salt, key)},
+		{name: "память больше предела", hash: phc("v=19", "m=4194304,t=1,p=1", salt, key)},
+		{name: "итераций больше предела", hash: phc("v=19", "m=64,t=100000,p=1", salt, key)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := alg.Verify(tt.hash, "password")
+			if !errors.Is(err, ErrMalformedHash) {
+				t.Errorf("Verify ошибка = %v, ожидалась %v", err, ErrMalformedHash)
+			}
+			if ok {
+				t.Error("Verify = true для поврежденного хеша")
+			}
+			if !alg.NeedsRehash(tt.hash) {
+				t.Error("NeedsRehash = false для поврежденного хеша")
+			}
+		})
+	}
+}
+
+func TestAlgorithmParamsOutOfRange(t *testing.T) {
+	argonTests := []struct {
+		name   string
+		params Argon2idParams
+	}{
+		{name: "t=0", params: Argon2idParams{Memory: 64, Time: 0, Threads: 1, SaltLength: 16, KeyLength: 32}},
+		{name: "p=0", params: Argon2idParams{Memory: 64, Time: 1, Threads: 0, SaltLength: 16, KeyLength: 32}},
+		{name: "память меньше 8 на поток", params: Argon2idParams{Memory: 15, Time: 1, Threads: 2, SaltLength: 16, KeyLength: 32}},
+		{name: "память больше предела", params: Argon2idParams{Memory: argon2MaxMemory + 1, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32}},
+		{name: "итераций больше предела", params: Argon2idParams{Memory: 64, Time: argon2MaxTime + 1, Threads: 1, SaltLength: 16, KeyLength: 32}},
+		{name: "короткая соль", params: Argon2idParams{Memory: 64, Time: 1, Threads: 1, SaltLength: 7, KeyLength: 32}},
+		{name: "короткий ключ", params: Argon2idParams{Memory: 64, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 15}},
+	}
+	for _, tt := range argonTests {
+		t.Run("argon2id "+tt.name, func(t *testing.T) {
+			if _, err := NewArgon2id(tt.params); !errors.Is(err, ErrIncompatibleParam) {
+				t.Errorf("NewArgon2id ошибка = %v, ожидалась %v", err, ErrIncompatibleParam)
+			}
+		})
+	}
+
+	for _, cost := range []int{bcrypt.MinCost - 1, bcrypt.MaxCost + 1} {
+		t.Run(fmt.Sprintf("bcrypt cost=%d", cost), func(t *testing.T) {
+			if _, err := NewBcrypt(cost); !errors.Is(err, ErrIncompatibleParam) {
+				t.Errorf("NewBcrypt ошибка = %v, ожидалась %v", err, ErrIncompatibleParam)
+			}
+		})
+	}
+
+	t.Run("неизвестный алгоритм", func(t *testing.T) {
+		_, err := NewHasher(config.PasswordHashing{
+			PasswordHashAlgorithm: "md5",
+			BcryptCost:            bcrypt.MinCost,
+			Argon2Memory:          testParams.Memory,
+			Argon2Time:            testParams.Time,
+			Argon2Threads:         testParams.Threads,
+			Argon2SaltLength:      testParams.SaltLength,
+			Argon2KeyLength:       testParams.KeyLength,
+		})
+		if !errors.Is(err, ErrUnknownAlgorithm) {
+			t.Errorf("NewHasher ошибка = %v, ожидалась %v", err, ErrUnknownAlgorithm)
+		}
+	})
+}
+
+func TestHasherRehashFromBcrypt(t *testing.T) {
+	legacy := testHasher(t, "bcrypt")
+	current := testHasher(t, "argon2id")
+
+	bcryptHash, err := legacy.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		hasher     *Hasher
+		password   string
+		wantOK     bool
+		wantRehash bool
+	}{
+		{name: "bcrypt при текущем bcrypt", hasher: legacy, password: "correct horse", wantOK: true, wantRehash: false},
+		{name: "bcrypt при текущем argon2id", hasher: current, password: "correct horse", wantOK: true, wantRehash: true},
+		{name: "неверный пароль к bcrypt", hasher: current, password: "wrong", wantOK: false, wantRehash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.hasher.Verify(bcryptHash, tt.password)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("Verify = %v, ожидалось %v", ok, tt.wantOK)
+			}
+			if rehash := tt.hasher.NeedsRehash(bcryptHash); rehash != tt.wantRehash {
+				t.Errorf("NeedsRehash = %v, ожидалось %v", rehash, tt.wantRehash)
+			}
+		})
+	}
+
+	// после входа пароль перехеширован текущим алгоритмом
+	argonHash, err := current.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(argonHash, argon2idPrefix) {
+		t.Fatalf("новый хеш не argon2id: %s", argonHash)
+	}
+	if current.NeedsRehash(argonHash) {
+		t.Error("NeedsRehash = true для нового хеша")
+	}
+	if ok, err := legacy.Verify(argonHash, "correct horse"); err != nil || !ok {
+		t.Errorf("после отката на bcrypt argon2id-хеш не проверяется: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestHasherVerifyRejects(t *testing.T) {
+	hasher := testHasher(t, "argon2id")
+
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr error
+	}{
+		{name: "пустой хеш", hash: "", wantErr: nil},
+		{name: "неизвестный формат", hash: "$1$salt$digest", wantErr: ErrUnknownHash},
+		{name: "открытый текст", hash: "password", wantErr: ErrUnknownHash},
+		{name: "поврежденный bcrypt", hash: "$2a$04$short", wantErr: ErrMalformedHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := hasher.Verify(tt.hash, "password")
+			if ok {
+				t.Error("Verify = true")
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Verify ошибка = %v, ожидалось nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify ошибка = %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBcryptPasswordTooLong(t *testing.T) {
+	alg, err := NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("NewBcrypt: %v", err)
+	}
+
+	long := strings.Repeat("a", 73)
+	if _, err := alg.Hash(long); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("Hash ошибка = %v, ожидалась %v", err, ErrPasswordTooLong)
+	}
+
+	// пароль длиннее 72 байт не должен совпадать с хешем своего префикса
+	hash, err := alg.Hash(long[:72])
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if ok, _ := alg.Verify(hash, long); ok {
+		t.Error("Verify = true для пароля длиннее 72 байт")
+	}
+}
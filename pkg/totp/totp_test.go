@@ -0,0 +1,180 @@
+package totp
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret Ключ из RFC 6238, приложение B: ASCII "12345678901234567890" в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 Векторы SHA1 из RFC 6238, приложение B. В RFC коды
+// 8-значные, здесь сравниваются их последние Digits цифр
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		step int64
+		rfc  string
+	}{
+		{unix: 59, step: 0x1, rfc: "94287082"},
+		{unix: 1111111109, step: 0x23523EC, rfc: "07081804"},
+		{unix: 1111111111, step: 0x23523ED, rfc: "14050471"},
+		{unix: 1234567890, step: 0x273EF07, rfc: "89005924"},
+		{unix: 2000000000, step: 0x3F940AA, rfc: "69279037"},
+		{unix: 20000000000, step: 0x27BC86AA, rfc: "65353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rfc, func(t *testing.T) {
+			at := time.Unix(tt.unix, 0).UTC()
+			if step := Step(at); step != tt.step {
+				t.Fatalf("Step = %#x, ожидалось %#x", step, tt.step)
+			}
+
+			want := tt.rfc[len(tt.rfc)-Digits:]
+			code, err := Code(rfcSecret, tt.step)
+			if err != nil {
+				t.Fatalf("Code: %v", err)
+			}
+			if code != want {
+				t.Errorf("Code = %s, ожидалось %s", code, want)
+			}
+
+			step, ok, err := Validate(rfcSecret, want, at)
+			if err != nil || !ok || step != tt.step {
+				t.Errorf("Validate = (%#x, %v, %v), ожидалось (%#x, true, nil)", step, ok, err, tt.step)
+			}
+		})
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	current := Step(at)
+
+	tests := []struct {
+		name   string
+		offset int64
+		wantOK bool
+	}{
+		{name: "текущий шаг", offset: 0, wantOK: true},
+		{name: "предыдущий шаг", offset: -1, wantOK: true},
+		{name: "следующий шаг", offset: 1, wantOK: true},
+		{name: "два шага назад", offset: -2, wantOK: false},
+		{name: "два шага вперед", offset: 2, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, current+tt.offset)
+			if err != nil {
+				t.Fatalf("Code: %v", err)
+			}
+
+			step, ok, err := Validate(rfcSecret, code, at)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("Validate ok = %v, ожидалось %v", ok, tt.wantOK)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("Validate step = %d, ожидалось %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+// TestValidateStepReuse Код остается действительным в соседнем шаге, поэтому
+// Validate возвращает шаг кода, а не текущий: по нему вызывающий отклоняет
+// повторное предъявление того же кода
+func TestValidateStepReuse(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+	code, err := Code(rfcSecret, Step(at))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+
+	first, ok, err := Validate(rfcSecret, code, at)
+	if err != nil || !ok {
+		t.Fatalf("Validate = (%v, %v)", ok, err)
+	}
+	again, ok, err := Validate(rfcSecret, code, at.Add(Period))
+	if err != nil || !ok {
+		t.Fatalf("Validate через шаг = (%v, %v)", ok, err)
+	}
+	if again != first {
+		t.Errorf("тот же код дал шаг %d вместо %d", again, first)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	tests := []struct {
+		name    string
+		secret  string
+		code    string
+		wantErr error
+	}{
+		{name: "неверный код", secret: rfcSecret, code: "000000"},
+		{name: "короткий код", secret: rfcSecret, code: "28708"},
+		{name: "8-значный код из RFC", secret: rfcSecret, code: "94287082"},
+		{name: "пустой код", secret: rfcSecret, code: ""},
+		{name: "секрет не base32", secret: "not-base32!", code: "287082", wantErr: ErrInvalidSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok, err := Validate(tt.secret, tt.code, at)
+			if ok {
+				t.Error("Validate = true")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate ошибка = %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// секрет в нижнем регистре тоже принимается
+	if _, ok, err := Validate(strings.ToLower(rfcSecret), "287082", at); err != nil || !ok {
+		t.Errorf("Validate с секретом в нижнем регистре = (%v, %v)", ok, err)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("секрет не base32: %v", err)
+	}
+	if len(key) != 20 {
+		t.Errorf("длина секрета %d байт, ожидалось 20", len(key))
+	}
+}
+
+func TestURI(t *testing.T) {
+	raw := URI("Task Manager", "user@example.com", rfcSecret)
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("неожиданная ссылка: %s", raw)
+	}
+	if u.Path != "/Task Manager:user@example.com" {
+		t.Errorf("метка = %q", u.Path)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{
+		"secret": rfcSecret, "issuer": "Task Manager", "algorithm": "SHA1", "digits": "6", "period": "30",
+	} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, ожидалось %q", key, got, want)
+		}
+	}
+}